@@ -0,0 +1,205 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type accessZonesCollector struct {
+	zonesTotal        *prometheus.Desc
+	zoneInfo          *prometheus.Desc
+	zoneAuthProviders *prometheus.Desc
+	zoneAuthProvider  *prometheus.Desc
+	zoneNfsExports    *prometheus.Desc
+	zoneSmbShares     *prometheus.Desc
+	zoneQuotas        *prometheus.Desc
+}
+
+const zoneSubSystem = "zone"
+
+var zoneQuotasFlag *bool
+
+func init() {
+	registerCollector("access_zones", defaultDisabled, NewAccessZonesCollector)
+
+	//Access zone quota count flag.
+	zoneQuotasFlagName := "collector.access_zones.quotas"
+	zoneQuotasFlagHelp := "Count the quotas in every access zone, this lists every quota on the cluster (default: true)."
+	zoneQuotasFlag = kingpin.Flag(zoneQuotasFlagName, zoneQuotasFlagHelp).Default("true").Bool()
+}
+
+//NewAccessZonesCollector returns a new Collector exposing the access zones and what is configured in them.
+func NewAccessZonesCollector() (Collector, error) {
+	return &accessZonesCollector{
+		zonesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "total"),
+			"Total number of access zones on the cluster.",
+			nil, ConstLabels,
+		),
+		zoneInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "info"),
+			"Contains information about the access zone in labels. Always returns a 1.",
+			[]string{"zone", "zone_id", "path", "system"}, ConstLabels,
+		),
+		zoneAuthProviders: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "auth_providers"),
+			"Number of authentication providers of the access zone.",
+			[]string{"zone"}, ConstLabels,
+		),
+		zoneAuthProvider: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "auth_provider"),
+			"Always 1 for every authentication provider of the access zone.",
+			[]string{"zone", "provider"}, ConstLabels,
+		),
+		zoneNfsExports: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "nfs_exports"),
+			"Number of NFS exports in the access zone.",
+			[]string{"zone"}, ConstLabels,
+		),
+		zoneSmbShares: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "smb_shares"),
+			"Number of SMB shares in the access zone.",
+			[]string{"zone"}, ConstLabels,
+		),
+		zoneQuotas: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubSystem, "quotas"),
+			"Number of quotas of each type in the access zone. A quota is in the zone with the longest base path containing it.",
+			[]string{"zone", "type"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *accessZonesCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+	zones, err := isiclient.GetZones(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.zonesTotal, prometheus.GaugeValue, float64(len(zones.Zones)))
+
+	for _, zone := range zones.Zones {
+		ch <- prometheus.MustNewConstMetric(c.zoneInfo, prometheus.GaugeValue, 1, zone.Name, fmt.Sprintf("%v", zone.ZoneID),
+			zone.Path, strconv.FormatBool(zone.System))
+		ch <- prometheus.MustNewConstMetric(c.zoneAuthProviders, prometheus.GaugeValue, float64(len(zone.AuthProviders)), zone.Name)
+		for _, provider := range zone.AuthProviders {
+			ch <- prometheus.MustNewConstMetric(c.zoneAuthProvider, prometheus.GaugeValue, 1, zone.Name, provider)
+		}
+
+		exports, err := countNfsExports(zone.Name)
+		if err != nil {
+			log.Warnf("Unable to count nfs exports of zone %s: %s", zone.Name, err)
+			errCount++
+		} else {
+			ch <- prometheus.MustNewConstMetric(c.zoneNfsExports, prometheus.GaugeValue, float64(exports), zone.Name)
+		}
+
+		shares, err := countSmbShares(zone.Name)
+		if err != nil {
+			log.Warnf("Unable to count smb shares of zone %s: %s", zone.Name, err)
+			errCount++
+		} else {
+			ch <- prometheus.MustNewConstMetric(c.zoneSmbShares, prometheus.GaugeValue, float64(shares), zone.Name)
+		}
+	}
+
+	if *zoneQuotasFlag {
+		err = c.updateQuotas(ch, zones.Zones)
+		if err != nil {
+			log.Warnf("Unable to count quotas per zone: %s", err)
+			errCount++
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+//updateQuotas counts the quotas of every type under each zone. Nothing is reported unless every page of quotas was read.
+func (c *accessZonesCollector) updateQuotas(ch chan<- prometheus.Metric, zones []isiclient.IsiZone) error {
+	counts := make(map[string]map[string]int)
+	for _, zone := range zones {
+		counts[zone.Name] = make(map[string]int)
+	}
+
+	it := isiclient.NewQuotasIterator(IsiCluster.Client, false, "all", false, "")
+	var page isiclient.IsiQuotas
+	for it.Next(&page) {
+		for _, quota := range page.Quotas {
+			zone := zoneOfPath(zones, quota.Path)
+			if zone == "" {
+				continue
+			}
+			counts[zone][quota.Type]++
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for zone, types := range counts {
+		for _, qtype := range []string{"directory", "user", "group", "default-user", "default-group"} {
+			ch <- prometheus.MustNewConstMetric(c.zoneQuotas, prometheus.GaugeValue, float64(types[qtype]), zone, qtype)
+		}
+	}
+	return nil
+}
+
+func countNfsExports(zone string) (int, error) {
+	var count int
+	it := isiclient.NewNfsExportsIterator(IsiCluster.Client, zone)
+	var page isiclient.IsiNfsExports
+	for it.Next(&page) {
+		count += len(page.Exports)
+	}
+	return count, it.Err()
+}
+
+func countSmbShares(zone string) (int, error) {
+	var count int
+	it := isiclient.NewSmbSharesIterator(IsiCluster.Client, zone)
+	var page isiclient.IsiSmbShares
+	for it.Next(&page) {
+		count += len(page.Shares)
+	}
+	return count, it.Err()
+}
+
+//zoneOfPath returns the name of the access zone with the longest base path containing path, or an empty string if there is none.
+//Every path is under the System zone so nested tenant zones win over it.
+func zoneOfPath(zones []isiclient.IsiZone, path string) string {
+	var (
+		name    string
+		longest = -1
+	)
+	for _, zone := range zones {
+		base := strings.TrimSuffix(zone.Path, "/")
+		if path != base && !strings.HasPrefix(path, base+"/") {
+			continue
+		}
+		if len(base) > longest {
+			name = zone.Name
+			longest = len(base)
+		}
+	}
+	return name
+}
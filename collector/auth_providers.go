@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+type authProvidersCollector struct {
+	healthy             *prometheus.Desc
+	providerInfo        *prometheus.Desc
+	providerOnline      *prometheus.Desc
+	adsTrustOnline      *prometheus.Desc
+	adsClockOffset      *prometheus.Desc
+	adsPasswordChanges  *prometheus.Desc
+	adsPasswordLifespan *prometheus.Desc
+	lsassOpRate         *prometheus.Desc
+	lsassTimeAvg        *prometheus.Desc
+}
+
+const authSubSystem = "auth"
+
+//lsassProtos are the protocol stats of the requests to and from the authentication daemon.
+var lsassProtos = []string{"lsass_in", "lsass_out"}
+
+func init() {
+	registerCollector("auth_providers", defaultDisabled, NewAuthProvidersCollector)
+}
+
+//NewAuthProvidersCollector returns a new Collector exposing the health of the authentication providers.
+func NewAuthProvidersCollector() (Collector, error) {
+	return &authProvidersCollector{
+		healthy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "healthy"),
+			"1 if every authentication provider and every trusted Active Directory domain is online, 0 if not or if their status could not be read.",
+			nil, ConstLabels,
+		),
+		providerInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "provider_info"),
+			"Contains the status and the server in use of the authentication provider in labels. Always returns a 1.",
+			[]string{"provider", "type", "zone", "status", "active_server"}, ConstLabels,
+		),
+		providerOnline: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "provider_online"),
+			"1 if the authentication provider is online, 0 if not.",
+			[]string{"provider", "type", "zone"}, ConstLabels,
+		),
+		adsTrustOnline: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "ads_trust_online"),
+			"1 if the domain trusted by the Active Directory provider is online, 0 if not.",
+			[]string{"provider", "domain", "trust_type", "dc"}, ConstLabels,
+		),
+		adsClockOffset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "ads_controller_time_offset_seconds"),
+			"Time of the domain controller minus the time of the exporter. Kerberos fails once the skew is over 5 minutes.",
+			[]string{"provider"}, ConstLabels,
+		),
+		adsPasswordChanges: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "ads_machine_password_changes"),
+			"1 if the machine account password of the Active Directory provider is changed periodically, 0 if not.",
+			[]string{"provider"}, ConstLabels,
+		),
+		adsPasswordLifespan: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "ads_machine_password_lifespan_seconds"),
+			"Maximum age of the machine account password of the Active Directory provider before it is changed.",
+			[]string{"provider"}, ConstLabels,
+		),
+		lsassOpRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "lsass_op_rate"),
+			"Rate of operations of the authentication daemon across the cluster.",
+			[]string{"proto"}, ConstLabels,
+		),
+		lsassTimeAvg: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, authSubSystem, "lsass_time_avg"),
+			"Average time of operations of the authentication daemon across the cluster, in microseconds.",
+			[]string{"proto"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *authProvidersCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+
+	//Auth is reported unhealthy whenever its state cannot be read.
+	healthy, err := c.updateProviders(ch)
+	if err != nil {
+		errCount++
+	}
+	adsHealthy, adsErrors := c.updateAds(ch)
+	errCount += adsErrors
+	ch <- prometheus.MustNewConstMetric(c.healthy, prometheus.GaugeValue, boolToFloat(healthy && adsHealthy && errCount == 0))
+
+	for _, proto := range lsassProtos {
+		if err := c.updateLsass(ch, proto); err != nil {
+			errCount++
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+//updateProviders reports the status of every provider. healthy is false if any provider is offline.
+func (c *authProvidersCollector) updateProviders(ch chan<- prometheus.Metric) (bool, error) {
+	resp, err := isiclient.GetAuthProvidersSummary(IsiCluster.Client)
+	if err != nil {
+		return false, err
+	}
+	healthy := true
+	for _, provider := range resp.ProviderInstances {
+		online := authOnline(provider.Status)
+		if !online {
+			healthy = false
+		}
+		ch <- prometheus.MustNewConstMetric(c.providerInfo, prometheus.GaugeValue, 1,
+			provider.Name, provider.Type, provider.ZoneName, provider.Status, provider.ActiveServer)
+		ch <- prometheus.MustNewConstMetric(c.providerOnline, prometheus.GaugeValue, boolToFloat(online),
+			provider.Name, provider.Type, provider.ZoneName)
+	}
+	return healthy, nil
+}
+
+//updateAds reports the machine account and the trusts of every Active Directory provider.
+//healthy is false if any trusted domain is offline, errCount is the number of failed api calls.
+func (c *authProvidersCollector) updateAds(ch chan<- prometheus.Metric) (healthy bool, errCount int64) {
+	resp, err := isiclient.GetAdsProviders(IsiCluster.Client)
+	if err != nil {
+		return false, 1
+	}
+	now := time.Now()
+	healthy = true
+	for _, ads := range resp.Ads {
+		ch <- prometheus.MustNewConstMetric(c.adsPasswordChanges, prometheus.GaugeValue, boolToFloat(ads.MachinePasswordChanges), ads.Name)
+		ch <- prometheus.MustNewConstMetric(c.adsPasswordLifespan, prometheus.GaugeValue, ads.MachinePasswordLifespan, ads.Name)
+		//The controller time is only known while a domain controller can be reached.
+		if ads.ControllerTime > 0 {
+			offset := ads.ControllerTime - float64(now.Unix())
+			ch <- prometheus.MustNewConstMetric(c.adsClockOffset, prometheus.GaugeValue, offset, ads.Name)
+		}
+
+		domains, err := isiclient.GetAdsDomains(IsiCluster.Client, ads.ID)
+		if err != nil {
+			log.Warnf("Unable to collect trusted domains of ads provider %s: %s", ads.Name, err)
+			healthy = false
+			errCount++
+			continue
+		}
+		for _, domain := range domains.Domains {
+			online := authOnline(domain.Status)
+			if !online {
+				healthy = false
+			}
+			ch <- prometheus.MustNewConstMetric(c.adsTrustOnline, prometheus.GaugeValue, boolToFloat(online),
+				ads.Name, domain.Domain, domain.TrustType, domain.DcName)
+		}
+	}
+	return healthy, errCount
+}
+
+//updateLsass reports the protocol stats of the authentication daemon. The stats engine call metrics are not emitted
+//because cluster_protocol emits them for the same key when --collector.protocol_common.lsass_in/out is set.
+func (c *authProvidersCollector) updateLsass(ch chan<- prometheus.Metric, proto string) error {
+	key := fmt.Sprintf("cluster.protostats.%s.total", proto)
+	resp, err := isiclient.GetProtoStat(IsiCluster.Client, key)
+	if err != nil {
+		log.Warnf("Unable to collect cluster protocol stats for protocol %s.", proto)
+		return err
+	}
+
+	for _, stat := range resp.Stats {
+		values, ok := stat.Value.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, value := range values {
+			var protoStat isiclient.IsiProtoStatTotal
+			j, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(j, &protoStat); err != nil {
+				return err
+			}
+			ch <- prometheus.MustNewConstMetric(c.lsassOpRate, prometheus.GaugeValue, protoStat.OpRate, proto)
+			ch <- prometheus.MustNewConstMetric(c.lsassTimeAvg, prometheus.GaugeValue, protoStat.TimeAvg, proto)
+		}
+	}
+	return nil
+}
+
+//authOnline reports whether a provider or domain status means it is usable. Local and file providers report active instead of online.
+func authOnline(status string) bool {
+	status = strings.ToLower(status)
+	return status == "online" || status == "active"
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type clientProtoCollector struct {
+	clientOpRate  *prometheus.Desc
+	clientInRate  *prometheus.Desc
+	clientOutRate *prometheus.Desc
+	clientTimeAvg *prometheus.Desc
+	clientCount   *prometheus.Desc
+}
+
+var (
+	clientTopFlag   *int
+	clientTopByFlag *string
+)
+
+func init() {
+	registerCollector("client_protocol", defaultDisabled, NewClientProtoCollector)
+	if !protosUpdated {
+		GetProtos()
+	}
+
+	//Client top N flags.
+	clientTopFlagName := "collector.client_protocol.top"
+	clientTopFlagHelp := "Number of clients to export per protocol, ranked by --collector.client_protocol.top-by, 0 exports all (default: 10)."
+	clientTopFlag = kingpin.Flag(clientTopFlagName, clientTopFlagHelp).Default("10").Int()
+	clientTopByFlagName := "collector.client_protocol.top-by"
+	clientTopByFlagHelp := "Ranking used for --collector.client_protocol.top. One of (ops, in, out, throughput, latency)."
+	clientTopByFlag = kingpin.Flag(clientTopByFlagName, clientTopByFlagHelp).Default("ops").Enum("ops", "in", "out", "throughput", "latency")
+}
+
+//NewClientProtoCollector returns a new Collector exposing the protocol statistics of the busiest clients.
+func NewClientProtoCollector() (Collector, error) {
+	if *clientTopFlag < 0 {
+		return nil, fmt.Errorf("--collector.client_protocol.top must not be negative: %v", *clientTopFlag)
+	}
+	return &clientProtoCollector{
+		clientOpRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "client_protocol", "op_rate"),
+			"Client protocol operation rate.",
+			[]string{"node", "proto", "client", "user"}, ConstLabels,
+		),
+		clientInRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "client_protocol", "in_rate"),
+			"Client protocol bytes in rate.",
+			[]string{"node", "proto", "client", "user"}, ConstLabels,
+		),
+		clientOutRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "client_protocol", "out_rate"),
+			"Client protocol bytes out rate.",
+			[]string{"node", "proto", "client", "user"}, ConstLabels,
+		),
+		clientTimeAvg: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "client_protocol", "time_avg"),
+			"Client protocol operation time avg, weighted by the operation rate of each operation class.",
+			[]string{"node", "proto", "client", "user"}, ConstLabels,
+		),
+		clientCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "client_protocol", "clients"),
+			"Number of clients with protocol activity, before only the top N are exported.",
+			[]string{"proto"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *clientProtoCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+	for proto, state := range protocolState {
+		// There are not client stats for lsass_in or nfs4
+		if !*state || proto == "nfs4" || proto == "lsass_in" {
+			continue
+		}
+		err := c.updateClients(ch, proto)
+		if err != nil {
+			log.Warnf("Unable to collect client stats for %s", proto)
+			errCount++
+		}
+	}
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+//clientActivity is the activity of one client on one node summed over every operation class.
+type clientActivity struct {
+	node    string
+	client  string
+	user    string
+	opRate  float64
+	inRate  float64
+	outRate float64
+	timeSum float64
+}
+
+//timeAvg returns the average operation time weighted by the rate of each class.
+func (a *clientActivity) timeAvg() float64 {
+	if a.opRate == 0 {
+		return 0
+	}
+	return a.timeSum / a.opRate
+}
+
+func (a *clientActivity) score(by string) float64 {
+	switch by {
+	case "in":
+		return a.inRate
+	case "out":
+		return a.outRate
+	case "throughput":
+		return a.inRate + a.outRate
+	case "latency":
+		return a.timeAvg()
+	default:
+		return a.opRate
+	}
+}
+
+func (c *clientProtoCollector) updateClients(ch chan<- prometheus.Metric, protocol string) error {
+	key := fmt.Sprintf("node.clientstats.proto.%v", protocol)
+	begin := time.Now()
+	resp, err := isiclient.GetProtoStat(IsiCluster.Client, key)
+	duration := time.Since(begin)
+	ch <- prometheus.MustNewConstMetric(statsEngineCallDuration, prometheus.GaugeValue, duration.Seconds(), key)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 1, key)
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 0, key)
+
+	clients := make(map[string]*clientActivity)
+	for _, stat := range resp.Stats {
+		values, ok := stat.Value.([]interface{})
+		if !ok {
+			continue
+		}
+		node := fmt.Sprintf("%v", stat.Devid)
+		for _, value := range values {
+			//Marshal the generic value back into json to get it into a struct
+			var clientStat isiclient.IsiClientStat
+			j, err := json.Marshal(value)
+			if err != nil {
+				log.Warnf("Could not marshal back into json: %v", err)
+				return err
+			}
+			err = json.Unmarshal(j, &clientStat)
+			if err != nil {
+				log.Warnf("Could not unmarshl into stuct: %v", err)
+				return err
+			}
+
+			user := clientStat.User.Name
+			if user == "" {
+				user = clientStat.User.ID
+			}
+			id := node + "|" + clientStat.RemoteAddr + "|" + user
+			activity, ok := clients[id]
+			if !ok {
+				activity = &clientActivity{node: node, client: clientStat.RemoteAddr, user: user}
+				clients[id] = activity
+			}
+			activity.opRate += clientStat.OperationRate
+			activity.inRate += clientStat.InRate
+			activity.outRate += clientStat.OutRate
+			activity.timeSum += clientStat.TimeAvg * clientStat.OperationRate
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.clientCount, prometheus.GaugeValue, float64(len(clients)), protocol)
+
+	ranked := make([]*clientActivity, 0, len(clients))
+	for _, activity := range clients {
+		ranked = append(ranked, activity)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].score(*clientTopByFlag) > ranked[j].score(*clientTopByFlag)
+	})
+	if *clientTopFlag > 0 && len(ranked) > *clientTopFlag {
+		ranked = ranked[:*clientTopFlag]
+	}
+	for _, a := range ranked {
+		ch <- prometheus.MustNewConstMetric(c.clientOpRate, prometheus.GaugeValue, a.opRate, a.node, protocol, a.client, a.user)
+		ch <- prometheus.MustNewConstMetric(c.clientInRate, prometheus.GaugeValue, a.inRate, a.node, protocol, a.client, a.user)
+		ch <- prometheus.MustNewConstMetric(c.clientOutRate, prometheus.GaugeValue, a.outRate, a.node, protocol, a.client, a.user)
+		ch <- prometheus.MustNewConstMetric(c.clientTimeAvg, prometheus.GaugeValue, a.timeAvg(), a.node, protocol, a.client, a.user)
+	}
+	return nil
+}
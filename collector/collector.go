@@ -13,6 +13,7 @@ package collector
 
 import (
 	"fmt"
+	"net/url"
 	"sync"
 	"time"
 
@@ -57,7 +58,8 @@ type isilonCollector struct {
 }
 
 // NewIsilonCollector creates a new IsilonCollector
-func NewIsilonCollector(fqdn string, port string, uname string, pwdenv string, site string, auth bool, qOnly bool, filters ...string) (*isilonCollector, error) {
+// params are the url parameters of the scrape, collectors implementing scrapeConfigurer read their per scrape settings from them.
+func NewIsilonCollector(fqdn string, port string, uname string, pwdenv string, site string, auth bool, qOnly bool, params url.Values, filters ...string) (*isilonCollector, error) {
 	if auth {
 		// Take the struct that was generated in main and use it as the configuration for connecting to the clusters.
 		IsiCluster.FQDN = fqdn
@@ -87,9 +89,6 @@ func NewIsilonCollector(fqdn string, port string, uname string, pwdenv string, s
 			if err != nil {
 				return nil, fmt.Errorf("Unable to get count of quotas from the system. %s", err)
 			}
-
-			flag := kingpin.Flag("collector.quota.retry", "Number of time to attempt collection of quota metrics (default: 3).").Default("3").Int64()
-			IsiCluster.Quotas.Retry = *flag
 		}
 
 		// Create descriptors for collector leve metrics.
@@ -152,6 +151,11 @@ func NewIsilonCollector(fqdn string, port string, uname string, pwdenv string, s
 				return nil, err
 			}
 			if len(f) == 0 || f[key] {
+				if configurer, ok := collector.(scrapeConfigurer); ok {
+					if err := configurer.Configure(params); err != nil {
+						return nil, fmt.Errorf("Invalid %s settings: %s", key, err)
+					}
+				}
 				collectors[key] = collector
 			}
 		}
@@ -207,3 +211,9 @@ type Collector interface {
 	// Get new metrics and expose them via prometheus registry.
 	Update(ch chan<- prometheus.Metric) error
 }
+
+// scrapeConfigurer is implemented by collectors whose flags can be overridden by the url parameters of a scrape.
+type scrapeConfigurer interface {
+	// Override the settings of the collector with the parameters that are set.
+	Configure(params url.Values) error
+}
@@ -13,9 +13,9 @@ package collector
 
 import (
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/hpanike/goisilon"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
-	"github.com/hpanike/goisilon"
 )
 
 var (
@@ -49,7 +49,6 @@ type Quotas struct {
 	Count  int64
 	Errors int64
 	Err    error
-	Retry  int64
 }
 
 //SetClusterConfigName will get the name from the isi config and set it as IsilonClusterConfigName inside IsiCluster.
@@ -0,0 +1,213 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type dataReductionCollector struct {
+	dedupeLogicalBytes           *prometheus.Desc
+	dedupeSavedBytes             *prometheus.Desc
+	dedupeEstimatedSavedBytes    *prometheus.Desc
+	dedupeEstimatedPhysicalBytes *prometheus.Desc
+	dedupeTotalBytes             *prometheus.Desc
+	dedupeRatio                  *prometheus.Desc
+	dedupeLastReport             *prometheus.Desc
+	reductionStat                *prometheus.Desc
+}
+
+var reductionKeysFlag *[]string
+
+//reductionKeyCache remembers which configured data reduction keys the cluster has so they are only listed once.
+//Older releases do not have the default keys, they are dropped instead of failing every scrape.
+type reductionKeyCache struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+var reductionKeys = &reductionKeyCache{}
+
+//Get returns the configured keys the cluster lists, listing them from the cluster until that succeeds once.
+func (r *reductionKeyCache) Get(configured []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys != nil {
+		return r.keys, nil
+	}
+
+	listed := make(map[string]bool)
+	it := isiclient.NewStatsKeysIterator(IsiCluster.Client)
+	var page isiclient.IsiStatsKeys
+	for it.Next(&page) {
+		for _, key := range page.Keys {
+			listed[key.Key] = true
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, key := range configured {
+		if !listed[key] {
+			log.Infof("Stats key %s is not available on this cluster and will not be collected.", key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	r.keys = keys
+	return r.keys, nil
+}
+
+func init() {
+	registerCollector("data_reduction", defaultDisabled, NewDataReductionCollector)
+
+	//Inline data reduction stats keys flag.
+	reductionKeysFlagName := "collector.data_reduction.key"
+	reductionKeysFlagHelp := "Stats engine key with inline data reduction (compression, dedupe) statistics to collect for every node, OneFS 8.2.2+. Keys the cluster does not list are skipped. May be repeated."
+	reductionKeysFlag = kingpin.Flag(reductionKeysFlagName, reductionKeysFlagHelp).Default(
+		"cluster.compression.overall.ratio",
+		"node.compression.overall.ratio",
+		"ifs.data.reduction.ratio",
+	).Strings()
+}
+
+//NewDataReductionCollector returns a new Collector exposing SmartDedupe savings and inline data reduction statistics.
+func NewDataReductionCollector() (Collector, error) {
+	return &dataReductionCollector{
+		dedupeLogicalBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "logical_bytes"),
+			"Logical bytes of data scanned by SmartDedupe.",
+			nil, ConstLabels,
+		),
+		dedupeSavedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "saved_bytes"),
+			"Logical bytes saved by SmartDedupe.",
+			nil, ConstLabels,
+		),
+		dedupeEstimatedSavedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "estimated_saved_bytes"),
+			"Bytes the last dedupe assessment estimates could be saved.",
+			nil, ConstLabels,
+		),
+		dedupeEstimatedPhysicalBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "estimated_physical_bytes"),
+			"Physical bytes the last dedupe assessment estimates the data would take after deduplication.",
+			nil, ConstLabels,
+		),
+		dedupeTotalBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "total_bytes"),
+			"Total bytes of the file system as seen by SmartDedupe.",
+			nil, ConstLabels,
+		),
+		dedupeRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "ratio"),
+			"Logical bytes divided by logical bytes after SmartDedupe savings, 1.0 means no savings.",
+			nil, ConstLabels,
+		),
+		dedupeLastReport: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dedupe", "last_report_timestamp"),
+			"Timestamp of the most recent dedupe job report.",
+			[]string{"job_type"}, ConstLabels,
+		),
+		reductionStat: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "data_reduction", "stat"),
+			"Current value of an inline data reduction stats engine key. Cluster wide keys are reported for node 0.",
+			[]string{"node", "key"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *dataReductionCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+	err := c.updateDedupe(ch)
+	if err != nil {
+		log.Warnf("Unable to collect dedupe savings: %s", err)
+		errCount++
+	}
+
+	keys, err := reductionKeys.Get(*reductionKeysFlag)
+	if err != nil {
+		log.Warnf("Unable to list the stats keys of the cluster: %s", err)
+		errCount++
+	}
+	for _, statKey := range keys {
+		begin := time.Now()
+		resp, err := isiclient.QueryStatsEngineSingleVal(IsiCluster.Client, statKey)
+		duration := time.Since(begin)
+		ch <- prometheus.MustNewConstMetric(statsEngineCallDuration, prometheus.GaugeValue, duration.Seconds(), statKey)
+		if err != nil {
+			log.Warnf("Error attempting to query stats engine with key %s: %s", statKey, err)
+			ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 1, statKey)
+			errCount++
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 0, statKey)
+		for _, stat := range resp.Stats {
+			if stat.Error != nil {
+				continue
+			}
+			node := fmt.Sprintf("%v", stat.Devid)
+			ch <- prometheus.MustNewConstMetric(c.reductionStat, prometheus.GaugeValue, stat.Value, node, statKey)
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+func (c *dataReductionCollector) updateDedupe(ch chan<- prometheus.Metric) error {
+	resp, err := isiclient.GetDedupeSummary(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	summary := resp.Summary
+	logical := summary.LogicalBlocks * summary.BlockSize
+	saved := summary.SavedLogicalBlocks * summary.BlockSize
+	ch <- prometheus.MustNewConstMetric(c.dedupeLogicalBytes, prometheus.GaugeValue, logical)
+	ch <- prometheus.MustNewConstMetric(c.dedupeSavedBytes, prometheus.GaugeValue, saved)
+	ch <- prometheus.MustNewConstMetric(c.dedupeEstimatedSavedBytes, prometheus.GaugeValue, summary.EstimatedSavedBlocks*summary.BlockSize)
+	ch <- prometheus.MustNewConstMetric(c.dedupeEstimatedPhysicalBytes, prometheus.GaugeValue, summary.EstimatedPhysicalBlocks*summary.BlockSize)
+	ch <- prometheus.MustNewConstMetric(c.dedupeTotalBytes, prometheus.GaugeValue, summary.TotalBlocks*summary.BlockSize)
+	if logical > 0 && logical > saved {
+		ch <- prometheus.MustNewConstMetric(c.dedupeRatio, prometheus.GaugeValue, logical/(logical-saved))
+	}
+
+	//Keep the newest report of each dedupe job type (Dedupe, DedupeAssessment).
+	latest := make(map[string]float64)
+	it := isiclient.NewDedupeReportsIterator(IsiCluster.Client)
+	var page isiclient.IsiDedupeReports
+	for it.Next(&page) {
+		for _, report := range page.Reports {
+			if report.Time > latest[report.JobType] {
+				latest[report.JobType] = report.Time
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	for jobType, t := range latest {
+		ch <- prometheus.MustNewConstMetric(c.dedupeLastReport, prometheus.GaugeValue, t, jobType)
+	}
+	return nil
+}
@@ -13,11 +13,13 @@ package collector
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type diskCollector struct {
@@ -26,15 +28,59 @@ type diskCollector struct {
 	diskXfersInRateAll  *prometheus.Desc
 	diskXfersOutRateAll *prometheus.Desc
 	diskLatencyAll      *prometheus.Desc
+	driveStats          map[string]*prometheus.Desc
 }
 
+var (
+	perDriveFlag   *bool
+	driveMediaFlag *string
+
+	//driveStatKeys maps the per drive stats engine keys, suffixed with the bay number, to metric names.
+	driveStatKeys = map[string]string{
+		"busy":           "disk_busy",
+		"iosched.queue":  "disk_iosched_queued",
+		"xfers.in.rate":  "disk_xfers_in_rate",
+		"xfers.out.rate": "disk_xfers_out_rate",
+		"access.latency": "disk_latency",
+		"bytes.in.rate":  "disk_bytes_in_rate",
+		"bytes.out.rate": "disk_bytes_out_rate",
+	}
+	driveStatHelp = map[string]string{
+		"busy":           "Current drive busy percentage represented in 0.0-1.0.",
+		"iosched.queue":  "Current queue depth for IO sceduler of the drive.",
+		"xfers.in.rate":  "Current drive ingest transfer rate.",
+		"xfers.out.rate": "Current drive egress transfer rate.",
+		"access.latency": "Current drive latency.",
+		"bytes.in.rate":  "Current drive bytes in rate.",
+		"bytes.out.rate": "Current drive bytes out rate.",
+	}
+)
+
 func init() {
 	registerCollector("disk", defaultEnabled, NewDiskCollector)
+
+	//Per drive stats flags.
+	perDriveFlagName := "collector.disk.per-drive"
+	perDriveFlagHelp := "Collect stats for every drive in addition to the .all keys (default: false)."
+	perDriveFlag = kingpin.Flag(perDriveFlagName, perDriveFlagHelp).Default("false").Bool()
+	driveMediaFlagName := "collector.disk.media"
+	driveMediaFlagHelp := "Media type of the drives per drive stats are collected for. One of (all, hdd, ssd)."
+	driveMediaFlag = kingpin.Flag(driveMediaFlagName, driveMediaFlagHelp).Default("all").Enum("all", "hdd", "ssd")
 }
 
 //NewDiskCollector returns a new Collector exposing node disk statistics.
 func NewDiskCollector() (Collector, error) {
+	driveStats := make(map[string]*prometheus.Desc)
+	for stat, name := range driveStatKeys {
+		driveStats[stat] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, name),
+			driveStatHelp[stat],
+			[]string{"node", "bay", "device", "media_type"}, ConstLabels,
+		)
+	}
+
 	return &diskCollector{
+		driveStats: driveStats,
 		diskBusyAll: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "disk_busy_all"),
 			"Current disk busy percentage represented in 0.0-1.0.",
@@ -97,9 +143,96 @@ func (c *diskCollector) Update(ch chan<- prometheus.Metric) error {
 			}
 		}
 	}
+
+	if *perDriveFlag {
+		err := c.updateDrives(ch)
+		if err != nil {
+			log.Warnf("Unable to collect per drive stats: %s", err)
+			errCount++
+		}
+	}
+
 	if errCount != 0 {
 		err := fmt.Errorf("There where %v errors", errCount)
 		return err
 	}
 	return nil
 }
+
+//driveLabels describe a drive in the per drive metrics.
+type driveLabels struct {
+	device    string
+	mediaType string
+}
+
+//updateDrives queries each per drive stat for every bay with a drive, joined with the drive info to label the device and media type.
+func (c *diskCollector) updateDrives(ch chan<- prometheus.Metric) error {
+	resp, err := isiclient.GetDriveInfo(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+
+	//Drives are keyed by node id, which is the devid of the stats engine, and bay.
+	drives := make(map[string]driveLabels)
+	bays := make(map[string]bool)
+	for _, node := range resp.Nodes {
+		for _, drive := range node.Drives {
+			if !drive.Present {
+				continue
+			}
+			if *driveMediaFlag != "all" && !strings.EqualFold(drive.MediaType, *driveMediaFlag) {
+				continue
+			}
+			bay := fmt.Sprintf("%v", drive.Baynum)
+			drives[fmt.Sprintf("%v/%s", node.ID, bay)] = driveLabels{device: drive.Devname, mediaType: drive.MediaType}
+			bays[bay] = true
+		}
+	}
+	if len(bays) == 0 {
+		return nil
+	}
+
+	var errCount int64
+	for stat, desc := range c.driveStats {
+		//Query the stat for every bay in a single call.
+		prefix := fmt.Sprintf("node.disk.%s.", stat)
+		var keys []string
+		for bay := range bays {
+			keys = append(keys, prefix+bay)
+		}
+		statKey := strings.Join(keys, ",")
+
+		begin := time.Now()
+		resp, err := isiclient.QueryStatsEngineSingleVal(IsiCluster.Client, statKey)
+		duration := time.Since(begin)
+		ch <- prometheus.MustNewConstMetric(statsEngineCallDuration, prometheus.GaugeValue, duration.Seconds(), prefix+"<bay>")
+		if err != nil {
+			log.Warnf("Error attempting to query stats engine with key %s<bay>: %s", prefix, err)
+			ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 1, prefix+"<bay>")
+			errCount++
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 0, prefix+"<bay>")
+
+		for _, s := range resp.Stats {
+			if s.Error != nil {
+				continue
+			}
+			node := fmt.Sprintf("%v", s.Devid)
+			bay := strings.TrimPrefix(s.Key, prefix)
+			drive, ok := drives[node+"/"+bay]
+			if !ok {
+				continue
+			}
+			val := s.Value
+			if stat == "busy" {
+				val = val / 10
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, val, node, bay, drive.device, drive.mediaType)
+		}
+	}
+	if errCount != 0 {
+		return fmt.Errorf("There where %v errors", errCount)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type filepoolCollector struct {
+	policyTotal        *prometheus.Desc
+	policyInfo         *prometheus.Desc
+	policyApplyOrder   *prometheus.Desc
+	jobTimestamp       *prometheus.Desc
+	jobElapsed         *prometheus.Desc
+	policyFilesMatched *prometheus.Desc
+	policyDirsMatched  *prometheus.Desc
+	policyBytesMoved   *prometheus.Desc
+	policyInJob        *prometheus.Desc
+}
+
+var smartpoolsLookbackFlag *time.Duration
+
+func init() {
+	registerCollector("filepool", defaultDisabled, NewFilepoolCollector)
+
+	//SmartPools job report lookback flag.
+	smartpoolsLookbackFlagName := "collector.filepool.job-lookback"
+	smartpoolsLookbackFlagHelp := "How far back to look for the most recent SmartPools job report (default: 168h)."
+	smartpoolsLookbackFlag = kingpin.Flag(smartpoolsLookbackFlagName, smartpoolsLookbackFlagHelp).Default("168h").Duration()
+}
+
+//NewFilepoolCollector returns a new Collector exposing file pool policies and the results of the last SmartPools job.
+func NewFilepoolCollector() (Collector, error) {
+	return &filepoolCollector{
+		policyTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "filepool", "policies_total"),
+			"Total number of file pool policies, not counting the default policy.",
+			nil, ConstLabels,
+		),
+		policyInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "filepool", "policy_info"),
+			"Contains the settings of the file pool policy in labels. Always returns a 1. The default policy is named default and has is_default true.",
+			[]string{"policy", "is_default", "target_tier", "protection", "ssd_strategy", "state"}, ConstLabels,
+		),
+		policyApplyOrder: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "filepool", "policy_apply_order"),
+			"Order in which the file pool policy is applied, lower first.",
+			[]string{"policy"}, ConstLabels,
+		),
+		jobTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smartpools", "job_timestamp"),
+			"Timestamp of the report of the most recent SmartPools job.",
+			[]string{"job_id"}, ConstLabels,
+		),
+		jobElapsed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smartpools", "job_elapsed_seconds"),
+			"Seconds the most recent SmartPools job ran for.",
+			[]string{"job_id"}, ConstLabels,
+		),
+		policyFilesMatched: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smartpools", "policy_files_matched"),
+			"Number of files the file pool policy matched in the most recent SmartPools job, by head or snapshot version.",
+			[]string{"policy", "version"}, ConstLabels,
+		),
+		policyDirsMatched: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smartpools", "policy_directories_matched"),
+			"Number of directories the file pool policy matched in the most recent SmartPools job, by head or snapshot version.",
+			[]string{"policy", "version"}, ConstLabels,
+		),
+		policyBytesMoved: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smartpools", "policy_bytes_moved"),
+			"Bytes the most recent SmartPools job moved for the file pool policy. Only exported where the job report includes it.",
+			[]string{"policy"}, ConstLabels,
+		),
+		policyInJob: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smartpools", "policy_in_job"),
+			"1 if the file pool policy has results in the most recent SmartPools job, 0 if it was not applied.",
+			[]string{"policy"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *filepoolCollector) Update(ch chan<- prometheus.Metric) error {
+	policies, err := isiclient.GetFilepoolPolicies(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.policyTotal, prometheus.GaugeValue, float64(len(policies.Policies)))
+	for _, policy := range policies.Policies {
+		c.updatePolicy(ch, policy.Name, false, policy.State, policy.Actions)
+		ch <- prometheus.MustNewConstMetric(c.policyApplyOrder, prometheus.GaugeValue, policy.ApplyOrder, policy.Name)
+	}
+
+	defaultPolicy, err := isiclient.GetFilepoolDefaultPolicy(IsiCluster.Client)
+	if err != nil {
+		log.Warnf("Unable to collect the default file pool policy: %s", err)
+	} else {
+		c.updatePolicy(ch, "default", true, "", defaultPolicy.DefaultPolicy.Actions)
+	}
+
+	return c.updateJob(ch, policies)
+}
+
+//updatePolicy emits the settings of a policy from its actions.
+//isDefault tells the default policy apart from a user policy that is also named default.
+func (c *filepoolCollector) updatePolicy(ch chan<- prometheus.Metric, name string, isDefault bool, state string, actions []isiclient.IsiFilepoolAction) {
+	settings := make(map[string]string)
+	for _, action := range actions {
+		settings[action.ActionType] = action.ActionParam
+	}
+	ch <- prometheus.MustNewConstMetric(c.policyInfo, prometheus.GaugeValue, 1, name, strconv.FormatBool(isDefault),
+		settings["set_data_storage_target"], settings["set_requested_protection"], settings["set_data_ssd_strategy"], state)
+}
+
+//updateJob emits the per policy results of the most recent SmartPools job.
+func (c *filepoolCollector) updateJob(ch chan<- prometheus.Metric, policies isiclient.IsiFilepoolPolicies) error {
+	var (
+		latest isiclient.IsiJobReport
+		found  bool
+	)
+	begin := time.Now().Add(-*smartpoolsLookbackFlag).Unix()
+	it := isiclient.NewJobReportsIterator(IsiCluster.Client, "SmartPools", begin)
+	var page isiclient.IsiJobReports
+	for it.Next(&page) {
+		for _, report := range page.Reports {
+			if !found || report.Time > latest.Time {
+				latest = report
+				found = true
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if !found {
+		log.Debugf("No SmartPools job report in the last %v", *smartpoolsLookbackFlag)
+		return nil
+	}
+
+	jobID := fmt.Sprintf("%v", latest.JobID)
+	ch <- prometheus.MustNewConstMetric(c.jobTimestamp, prometheus.GaugeValue, latest.Time, jobID)
+	ch <- prometheus.MustNewConstMetric(c.jobElapsed, prometheus.GaugeValue, latest.ElapsedTime, jobID)
+
+	inJob := make(map[string]bool)
+	for _, result := range latest.Results.Policies {
+		inJob[result.Name] = true
+		ch <- prometheus.MustNewConstMetric(c.policyFilesMatched, prometheus.GaugeValue, float64(result.FilesMatched.Head), result.Name, "head")
+		ch <- prometheus.MustNewConstMetric(c.policyFilesMatched, prometheus.GaugeValue, float64(result.FilesMatched.Snapshot), result.Name, "snapshot")
+		ch <- prometheus.MustNewConstMetric(c.policyDirsMatched, prometheus.GaugeValue, float64(result.DirectoriesMatched.Head), result.Name, "head")
+		ch <- prometheus.MustNewConstMetric(c.policyDirsMatched, prometheus.GaugeValue, float64(result.DirectoriesMatched.Snapshot), result.Name, "snapshot")
+		if result.BytesMoved != nil {
+			ch <- prometheus.MustNewConstMetric(c.policyBytesMoved, prometheus.GaugeValue, float64(*result.BytesMoved), result.Name)
+		}
+	}
+	for _, policy := range policies.Policies {
+		var applied float64
+		if inJob[policy.Name] {
+			applied = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.policyInJob, prometheus.GaugeValue, applied, policy.Name)
+	}
+	return nil
+}
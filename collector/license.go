@@ -0,0 +1,172 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+type licenseCollector struct {
+	licenseStatus       *prometheus.Desc
+	licenseExpiration   *prometheus.Desc
+	licenseDaysLeft     *prometheus.Desc
+	collectorUnlicensed *prometheus.Desc
+	running             map[string]bool
+}
+
+//licenseStates are the values of the status label of isilon_license_status.
+var licenseStates = []string{"activated", "evaluation", "expired", "unlicensed"}
+
+//licenseDependencies maps exporter collectors to the licensed features they need data from.
+var licenseDependencies = map[string][]string{
+	"quota":          {"SmartQuotas"},
+	"sync_iq":        {"SyncIQ"},
+	"filepool":       {"SmartPools"},
+	"snapshots":      {"SnapshotIQ"},
+	"data_reduction": {"SmartDedupe"},
+}
+
+//licenseExpirationLayouts are the layouts license expiration dates are tried against.
+var licenseExpirationLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+func init() {
+	registerCollector("license", defaultEnabled, NewLicenseCollector)
+}
+
+//NewLicenseCollector returns a new Collector exposing the license status and expiration of the cluster features.
+func NewLicenseCollector() (Collector, error) {
+	return &licenseCollector{
+		licenseStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "status"),
+			"1 if the feature license is in the status, 0 if not. Status is one of activated, evaluation, expired or unlicensed.",
+			[]string{"feature", "status"}, ConstLabels,
+		),
+		licenseExpiration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "expiration_timestamp"),
+			"Timestamp the feature license expires at. Only reported for licenses with an expiration date.",
+			[]string{"feature"}, ConstLabels,
+		),
+		licenseDaysLeft: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "days_remaining"),
+			"Number of days until the feature license expires, negative once expired. Only reported for licenses with an expiration date.",
+			[]string{"feature"}, ConstLabels,
+		),
+		collectorUnlicensed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "collector_unlicensed"),
+			"1 if an exporter collector running in this scrape depends on a feature that is expired or unlicensed, 0 if the feature is licensed.",
+			[]string{"collector", "feature"}, ConstLabels,
+		),
+	}, nil
+}
+
+//Configure records the collectors that run in this scrape, only those selected by collect[] when it is set.
+func (c *licenseCollector) Configure(params url.Values) error {
+	c.running = make(map[string]bool)
+	if selected := params["collect[]"]; len(selected) > 0 {
+		for _, collector := range selected {
+			c.running[collector] = true
+		}
+		return nil
+	}
+	for collector, enabled := range collectorState {
+		c.running[collector] = *enabled
+	}
+	return nil
+}
+
+func (c *licenseCollector) Update(ch chan<- prometheus.Metric) error {
+	resp, err := isiclient.GetLicenses(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	states := make(map[string]string)
+	for _, license := range resp.Licenses {
+		feature := license.Name
+		if feature == "" {
+			feature = license.ID
+		}
+		state := licenseState(license.Status)
+		states[strings.ToLower(feature)] = state
+		for _, s := range licenseStates {
+			var value float64
+			if s == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.licenseStatus, prometheus.GaugeValue, value, feature, s)
+		}
+
+		if license.Expiration == "" {
+			continue
+		}
+		expiration, ok := parseLicenseExpiration(license.Expiration)
+		if !ok {
+			log.Debugf("Unable to parse expiration %q of license %s", license.Expiration, feature)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.licenseExpiration, prometheus.GaugeValue, float64(expiration.Unix()), feature)
+		ch <- prometheus.MustNewConstMetric(c.licenseDaysLeft, prometheus.GaugeValue, expiration.Sub(now).Hours()/24, feature)
+	}
+
+	for collector, features := range licenseDependencies {
+		if !c.running[collector] {
+			continue
+		}
+		for _, feature := range features {
+			state, ok := states[strings.ToLower(feature)]
+			if !ok {
+				continue
+			}
+			var unlicensed float64
+			if state == "expired" || state == "unlicensed" {
+				unlicensed = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.collectorUnlicensed, prometheus.GaugeValue, unlicensed, collector, feature)
+		}
+	}
+	return nil
+}
+
+//licenseState maps the status reported by OneFS onto one of licenseStates.
+func licenseState(status string) string {
+	status = strings.ToLower(status)
+	switch {
+	case strings.Contains(status, "expired"):
+		return "expired"
+	case strings.Contains(status, "evaluation"):
+		return "evaluation"
+	case status == "activated" || status == "licensed":
+		return "activated"
+	default:
+		return "unlicensed"
+	}
+}
+
+func parseLicenseExpiration(value string) (time.Time, bool) {
+	for _, layout := range licenseExpirationLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
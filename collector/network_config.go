@@ -0,0 +1,282 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type networkConfigCollector struct {
+	poolInfo           *prometheus.Desc
+	poolInterfaceIPs   *prometheus.Desc
+	poolNodes          *prometheus.Desc
+	poolMemberNodes    *prometheus.Desc
+	subnetMtu          *prometheus.Desc
+	subnetPrefixLength *prometheus.Desc
+	ifaceUp            *prometheus.Desc
+	ifaceSpeed         *prometheus.Desc
+	ifaceMtu           *prometheus.Desc
+	ifaceStat          *prometheus.Desc
+}
+
+var ifaceCountFlag *int
+
+//ifaceStatKeys are the per interface stats engine keys, suffixed with the interface index.
+var ifaceStatKeys = []string{
+	"bytes.in.rate",
+	"bytes.out.rate",
+	"packets.in.rate",
+	"packets.out.rate",
+	"errors.in.rate",
+	"errors.out.rate",
+}
+
+func init() {
+	registerCollector("network_config", defaultDisabled, NewNetworkConfigCollector)
+
+	//Interface index flag.
+	ifaceCountFlagName := "collector.network_config.iface-stats"
+	ifaceCountFlagHelp := "Highest number of interfaces per node to query node.net.iface.* stats for (default: 8, 0 does not query interface stats)."
+	ifaceCountFlag = kingpin.Flag(ifaceCountFlagName, ifaceCountFlagHelp).Default("8").Int()
+}
+
+//NewNetworkConfigCollector returns a new Collector exposing SmartConnect pools, subnets and interfaces.
+func NewNetworkConfigCollector() (Collector, error) {
+	return &networkConfigCollector{
+		poolInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "pool_info"),
+			"Always 1, the labels describe the configuration of the network pool.",
+			[]string{"pool", "groupnet", "subnet", "zone", "alloc_method", "connect_policy", "failover_policy", "rebalance_policy", "dns_zone"}, ConstLabels,
+		),
+		poolInterfaceIPs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "pool_interface_ips"),
+			"Number of ip addresses of the network pool held by the interface.",
+			[]string{"pool", "node", "interface"}, ConstLabels,
+		),
+		poolNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "pool_nodes"),
+			"Number of nodes holding ip addresses of the network pool.",
+			[]string{"pool"}, ConstLabels,
+		),
+		poolMemberNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "pool_member_nodes"),
+			"Number of nodes with an interface that is a member of the network pool.",
+			[]string{"pool"}, ConstLabels,
+		),
+		subnetMtu: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "subnet_mtu"),
+			"MTU of the network subnet.",
+			[]string{"subnet", "groupnet", "addr_family"}, ConstLabels,
+		),
+		subnetPrefixLength: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "subnet_prefix_length"),
+			"Prefix length of the network subnet.",
+			[]string{"subnet", "groupnet", "addr_family"}, ConstLabels,
+		),
+		ifaceUp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "interface_up"),
+			"1 if the interface status is up, 0 if not.",
+			[]string{"node", "interface", "nic", "type"}, ConstLabels,
+		),
+		ifaceSpeed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "interface_speed"),
+			"Link speed of the interface as reported by the api.",
+			[]string{"node", "interface"}, ConstLabels,
+		),
+		ifaceMtu: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "network", "interface_mtu"),
+			"MTU of the interface.",
+			[]string{"node", "interface"}, ConstLabels,
+		),
+		ifaceStat: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "net_iface_rate"),
+			"Current per interface network rate from the node.net.iface.* stats.",
+			[]string{"node", "interface", "stat"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *networkConfigCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+	ifaces, err := c.getInterfaces()
+	ifacesOK := err == nil
+	if !ifacesOK {
+		log.Warnf("Unable to collect network interfaces: %s", err)
+		errCount++
+	} else {
+		c.updateInterfaces(ch, ifaces)
+	}
+
+	err = c.updatePools(ch, ifaces, ifacesOK)
+	if err != nil {
+		log.Warnf("Unable to collect network pools: %s", err)
+		errCount++
+	}
+
+	err = c.updateSubnets(ch)
+	if err != nil {
+		log.Warnf("Unable to collect network subnets: %s", err)
+		errCount++
+	}
+
+	for idx := 0; idx < *ifaceCountFlag; idx++ {
+		more, err := c.updateIfaceStats(ch, idx)
+		if err != nil {
+			log.Warnf("Unable to collect stats of interface %v: %s", idx, err)
+			errCount++
+			break
+		}
+		if !more {
+			break
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+func (c *networkConfigCollector) getInterfaces() ([]isiclient.IsiNetworkInterface, error) {
+	var ifaces []isiclient.IsiNetworkInterface
+	it := isiclient.NewNetworkInterfacesIterator(IsiCluster.Client)
+	var page isiclient.IsiNetworkInterfaces
+	for it.Next(&page) {
+		ifaces = append(ifaces, page.Interfaces...)
+	}
+	return ifaces, it.Err()
+}
+
+func (c *networkConfigCollector) updateInterfaces(ch chan<- prometheus.Metric, ifaces []isiclient.IsiNetworkInterface) {
+	for _, iface := range ifaces {
+		node := fmt.Sprintf("%v", iface.Lnn)
+		var up float64
+		if iface.Status == "up" {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.ifaceUp, prometheus.GaugeValue, up, node, iface.Name, iface.NicName, iface.Type)
+		ch <- prometheus.MustNewConstMetric(c.ifaceSpeed, prometheus.GaugeValue, iface.Speed, node, iface.Name)
+		ch <- prometheus.MustNewConstMetric(c.ifaceMtu, prometheus.GaugeValue, iface.Mtu, node, iface.Name)
+	}
+}
+
+//updatePools reports every network pool. The ip allocation is only reported when ifacesOK, as it comes from the interfaces.
+func (c *networkConfigCollector) updatePools(ch chan<- prometheus.Metric, ifaces []isiclient.IsiNetworkInterface, ifacesOK bool) error {
+	it := isiclient.NewNetworkPoolsIterator(IsiCluster.Client)
+	var page isiclient.IsiNetworkPools
+	var pools []isiclient.IsiNetworkPool
+	for it.Next(&page) {
+		pools = append(pools, page.Pools...)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	//Which interface holds which ip is only known from the interface owners.
+	type holder struct {
+		node  string
+		iface string
+	}
+	held := make(map[string]map[holder]int)
+	for _, iface := range ifaces {
+		for _, owner := range iface.Owners {
+			pool := strings.Join([]string{owner.Groupnet, owner.Subnet, owner.Pool}, ".")
+			if held[pool] == nil {
+				held[pool] = make(map[holder]int)
+			}
+			held[pool][holder{node: fmt.Sprintf("%v", iface.Lnn), iface: iface.Name}] += len(owner.IPAddrs)
+		}
+	}
+
+	for _, pool := range pools {
+		ch <- prometheus.MustNewConstMetric(c.poolInfo, prometheus.GaugeValue, 1, pool.ID, pool.Groupnet, pool.Subnet, pool.AccessZone,
+			pool.AllocMethod, pool.ScConnectPolicy, pool.ScFailoverPolicy, pool.RebalancePolicy, pool.ScDNSZone)
+
+		members := make(map[float64]bool)
+		for _, iface := range pool.Ifaces {
+			members[iface.Lnn] = true
+		}
+		ch <- prometheus.MustNewConstMetric(c.poolMemberNodes, prometheus.GaugeValue, float64(len(members)), pool.ID)
+
+		if !ifacesOK {
+			continue
+		}
+		nodes := make(map[string]bool)
+		for h, count := range held[pool.ID] {
+			if count > 0 {
+				nodes[h.node] = true
+			}
+			ch <- prometheus.MustNewConstMetric(c.poolInterfaceIPs, prometheus.GaugeValue, float64(count), pool.ID, h.node, h.iface)
+		}
+		ch <- prometheus.MustNewConstMetric(c.poolNodes, prometheus.GaugeValue, float64(len(nodes)), pool.ID)
+	}
+	return nil
+}
+
+func (c *networkConfigCollector) updateSubnets(ch chan<- prometheus.Metric) error {
+	it := isiclient.NewNetworkSubnetsIterator(IsiCluster.Client)
+	var page isiclient.IsiNetworkSubnets
+	for it.Next(&page) {
+		for _, subnet := range page.Subnets {
+			ch <- prometheus.MustNewConstMetric(c.subnetMtu, prometheus.GaugeValue, subnet.Mtu, subnet.ID, subnet.Groupnet, subnet.AddrFamily)
+			ch <- prometheus.MustNewConstMetric(c.subnetPrefixLength, prometheus.GaugeValue, subnet.Prefixlen, subnet.ID, subnet.Groupnet, subnet.AddrFamily)
+		}
+	}
+	return it.Err()
+}
+
+//updateIfaceStats queries the stats of the interface with the given index on every node.
+//more is false once no node has an interface with the index.
+func (c *networkConfigCollector) updateIfaceStats(ch chan<- prometheus.Metric, idx int) (more bool, err error) {
+	nameKey := fmt.Sprintf("node.net.iface.name.%v", idx)
+	keys := []string{nameKey}
+	for _, stat := range ifaceStatKeys {
+		keys = append(keys, fmt.Sprintf("node.net.iface.%s.%v", stat, idx))
+	}
+
+	//Query the name and every stat of the interface in a single call.
+	key := strings.Join(keys, ",")
+	begin := time.Now()
+	resp, err := isiclient.GetProtoStat(IsiCluster.Client, key)
+	duration := time.Since(begin)
+	ch <- prometheus.MustNewConstMetric(statsEngineCallDuration, prometheus.GaugeValue, duration.Seconds(), key)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 1, key)
+		return false, err
+	}
+	ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 0, key)
+
+	names := make(map[int]string)
+	for _, stat := range resp.Stats {
+		if name, ok := stat.Value.(string); ok && stat.Key == nameKey && name != "" {
+			names[stat.Devid] = name
+		}
+	}
+	for _, stat := range resp.Stats {
+		value, ok := stat.Value.(float64)
+		name, named := names[stat.Devid]
+		if !ok || !named {
+			continue
+		}
+		statName := strings.TrimSuffix(strings.TrimPrefix(stat.Key, "node.net.iface."), fmt.Sprintf(".%v", idx))
+		ch <- prometheus.MustNewConstMetric(c.ifaceStat, prometheus.GaugeValue, value, fmt.Sprintf("%v", stat.Devid), name, statName)
+	}
+	return len(names) > 0, nil
+}
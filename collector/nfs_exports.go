@@ -12,16 +12,33 @@ written permission of Adobe.
 package collector
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type nfsExportsCollector struct {
-	exportCount *prometheus.Desc
+	exportCount         *prometheus.Desc
+	exportInfo          *prometheus.Desc
+	exportClients       *prometheus.Desc
+	exportProblems      *prometheus.Desc
+	exportCheckProblems *prometheus.Desc
 }
 
+var nfsCheckFlag *bool
+
 func init() {
 	registerCollector("nfs_exports", defaultEnabled, NewNfsExportsCollector)
+
+	//NFS export check flag.
+	nfsCheckFlagName := "collector.nfs_exports.check"
+	nfsCheckFlagHelp := "Validate the nfs exports of every access zone and report the problems found (default: true)."
+	nfsCheckFlag = kingpin.Flag(nfsCheckFlagName, nfsCheckFlagHelp).Default("true").Bool()
 }
 
 //NewNfsExportsCollector exposed various metrics and information about nodes.
@@ -32,6 +49,26 @@ func NewNfsExportsCollector() (Collector, error) {
 			"Total number of NFS exports on a cluster.",
 			nil, ConstLabels,
 		),
+		exportInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nfs", "export_info"),
+			"Always 1, the labels describe the configuration of the NFS export.",
+			[]string{"id", "zone", "paths", "read_only", "map_root", "security_flavors"}, ConstLabels,
+		),
+		exportClients: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nfs", "export_clients"),
+			"Number of clients configured on the NFS export by access (clients, read_only, read_write, root).",
+			[]string{"id", "zone", "access"}, ConstLabels,
+		),
+		exportProblems: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nfs", "export_problems"),
+			"Number of problems found when validating the NFS export.",
+			[]string{"id", "zone"}, ConstLabels,
+		),
+		exportCheckProblems: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nfs", "export_check_problems_total"),
+			"Number of problems found when validating the NFS exports of an access zone.",
+			[]string{"zone"}, ConstLabels,
+		),
 	}, nil
 }
 
@@ -42,5 +79,77 @@ func (c *nfsExportsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	ch <- prometheus.MustNewConstMetric(c.exportCount, prometheus.GaugeValue, resp.Summary.Count)
-	return err
+
+	//Exports are listed per access zone.
+	zones, err := isiclient.GetZones(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	var errCount int64
+	for _, zone := range zones.Zones {
+		err = c.updateZone(ch, zone.Name)
+		if err != nil {
+			log.Warnf("Unable to collect nfs exports of zone %s: %s", zone.Name, err)
+			errCount++
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+func (c *nfsExportsCollector) updateZone(ch chan<- prometheus.Metric, zone string) error {
+	var exports []isiclient.IsiNfsExport
+	it := isiclient.NewNfsExportsIterator(IsiCluster.Client, zone)
+	var page isiclient.IsiNfsExports
+	for it.Next(&page) {
+		exports = append(exports, page.Exports...)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		id := fmt.Sprintf("%v", export.ID)
+		var mapRoot string
+		if export.MapRoot.Enabled {
+			mapRoot = export.MapRoot.User.ID
+		}
+		flavors := append([]string(nil), export.SecurityFlavors...)
+		sort.Strings(flavors)
+		ch <- prometheus.MustNewConstMetric(c.exportInfo, prometheus.GaugeValue, 1, id, zone,
+			strings.Join(export.Paths, ","), fmt.Sprintf("%v", export.ReadOnly), mapRoot, strings.Join(flavors, ","))
+
+		clients := map[string][]string{
+			"clients":    export.Clients,
+			"read_only":  export.ReadOnlyClients,
+			"read_write": export.ReadWriteClients,
+			"root":       export.RootClients,
+		}
+		for access, list := range clients {
+			ch <- prometheus.MustNewConstMetric(c.exportClients, prometheus.GaugeValue, float64(len(list)), id, zone, access)
+		}
+	}
+
+	if !*nfsCheckFlag {
+		return nil
+	}
+	check, err := isiclient.GetNfsExportsCheck(IsiCluster.Client, zone)
+	if err != nil {
+		return err
+	}
+	problems := make(map[string]int)
+	for _, problem := range check.Checks {
+		log.Debugf("NFS export %v in zone %s: %s", problem.ID, zone, problem.Message)
+		problems[fmt.Sprintf("%v", problem.ID)]++
+	}
+	for _, export := range exports {
+		id := fmt.Sprintf("%v", export.ID)
+		ch <- prometheus.MustNewConstMetric(c.exportProblems, prometheus.GaugeValue, float64(problems[id]), id, zone)
+	}
+	ch <- prometheus.MustNewConstMetric(c.exportCheckProblems, prometheus.GaugeValue, float64(len(check.Checks)), zone)
+	return nil
 }
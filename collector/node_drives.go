@@ -0,0 +1,160 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//driveStateEntry is the state a drive was last seen in and since when.
+type driveStateEntry struct {
+	State string
+	Since time.Time
+	Seen  time.Time
+}
+
+//driveStateTracker remembers when each drive entered its current state across scrapes.
+//The api does not say how long a drive has been in a state so this is only as old as the exporter.
+type driveStateTracker struct {
+	mu      sync.Mutex
+	entries map[string]driveStateEntry
+}
+
+var driveStates = &driveStateTracker{
+	entries: make(map[string]driveStateEntry),
+}
+
+//Observe records the state of a drive and returns how long it has been in that state.
+func (t *driveStateTracker) Observe(id string, state string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[id]
+	if !ok || entry.State != state {
+		entry = driveStateEntry{State: state, Since: now}
+	}
+	entry.Seen = now
+	t.entries[id] = entry
+	return now.Sub(entry.Since)
+}
+
+//Prune forgets drives that have not been seen since before.
+func (t *driveStateTracker) Prune(before time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, entry := range t.entries {
+		if entry.Seen.Before(before) {
+			delete(t.entries, id)
+		}
+	}
+}
+
+//driveFirmwareEntry is a cached drive firmware lookup. Failed lookups are cached too so they are not retried every scrape.
+type driveFirmwareEntry struct {
+	Current string
+	Desired string
+	Expires time.Time
+}
+
+//driveFirmwareCache caches the firmware of drives by node id, bay and serial across scrapes.
+type driveFirmwareCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]driveFirmwareEntry
+}
+
+var driveFirmware = &driveFirmwareCache{
+	entries: make(map[string]driveFirmwareEntry),
+}
+
+//SetTTL sets how long a looked up firmware is kept for.
+func (f *driveFirmwareCache) SetTTL(ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ttl = ttl
+}
+
+//Get returns the cached firmware of a drive. ok is false if there is no unexpired entry.
+func (f *driveFirmwareCache) Get(id string, now time.Time) (driveFirmwareEntry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := f.entries[id]
+	if !ok || now.After(entry.Expires) {
+		return driveFirmwareEntry{}, false
+	}
+	return entry, true
+}
+
+//Set caches the firmware of a drive.
+func (f *driveFirmwareCache) Set(id string, current string, desired string, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries[id] = driveFirmwareEntry{Current: current, Desired: desired, Expires: now.Add(f.ttl)}
+}
+
+//Prune drops every expired entry so replaced drives do not stay cached.
+func (f *driveFirmwareCache) Prune(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, entry := range f.entries {
+		if now.After(entry.Expires) {
+			delete(f.entries, id)
+		}
+	}
+}
+
+//lookupFirmware returns the firmware of a drive from the cache, asking the drive on a miss.
+//Once the lookup budget of the scrape is spent misses return no firmware.
+func (c *nodeStatusCollector) lookupFirmware(nodeLNN string, nodeID string, bayID string, drive isiclient.IsiNodeDrive, now time.Time) (current string, desired string) {
+	id := nodeID + "/" + bayID + "/" + drive.Serial
+	if entry, ok := driveFirmware.Get(id, now); ok {
+		return entry.Current, entry.Desired
+	}
+	if c.firmwareBudget <= 0 {
+		return "", ""
+	}
+	c.firmwareBudget--
+
+	resp, err := isiclient.GetDriveFirmware(IsiCluster.Client, nodeLNN, bayID)
+	if err == nil && len(resp.Drives) > 0 {
+		current, desired = resp.Drives[0].CurrentFirmware, resp.Drives[0].DesiredFirmware
+	}
+	driveFirmware.Set(id, current, desired, now)
+	return current, desired
+}
+
+//updateDriveDetails emits the firmware and state age metrics of a drive.
+func (c *nodeStatusCollector) updateDriveDetails(ch chan<- prometheus.Metric, nodeLNN string, nodeID string, bayID string, drive isiclient.IsiNodeDrive, now time.Time) {
+	current, desired := drive.Firmware.CurrentFirmware, drive.Firmware.DesiredFirmware
+	//Only ask the drive itself if the node listing did not include the firmware.
+	if current == "" && drive.Present {
+		current, desired = c.lookupFirmware(nodeLNN, nodeID, bayID, drive, now)
+	}
+	ch <- prometheus.MustNewConstMetric(c.nodeDriveInfo, prometheus.GaugeValue, 1, nodeLNN, nodeID, bayID, drive.Devname, drive.Model, drive.Serial, drive.Purpose, current, desired)
+
+	if current != "" {
+		//A drive without a desired firmware has nothing to be updated to.
+		var compliant float64
+		if desired == "" || current == desired {
+			compliant = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.nodeDriveFirmwareCurrent, prometheus.GaugeValue, compliant, nodeLNN, nodeID, bayID, drive.Devname)
+	}
+
+	age := driveStates.Observe(nodeID+"/"+bayID, drive.UIState, now)
+	ch <- prometheus.MustNewConstMetric(c.nodeDriveStateSeconds, prometheus.GaugeValue, age.Seconds(), nodeLNN, nodeID, bayID, drive.Devname, drive.UIState)
+}
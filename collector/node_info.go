@@ -12,11 +12,14 @@ package collector
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type nodeStatusCollector struct {
@@ -24,17 +27,57 @@ type nodeStatusCollector struct {
 	nodePowerSupply *prometheus.Desc
 	nodeDriveState  *prometheus.Desc
 	nodeInfo        *prometheus.Desc
+
+	nodeDriveInfo            *prometheus.Desc
+	nodeDriveFirmwareCurrent *prometheus.Desc
+	nodeDriveStateSeconds    *prometheus.Desc
+
+	nodeNvramBatteryVoltage *prometheus.Desc
+	nodeNvramBatteryInfo    *prometheus.Desc
+	nodeNvramChargeStatus   *prometheus.Desc
+	nodeBatteryNextTest     *prometheus.Desc
+	nodeBatteryLastTest     *prometheus.Desc
+	nodeNvramPresentSize    *prometheus.Desc
+	nodeNvramSupportedSize  *prometheus.Desc
+	nodeNvramSizeMismatch   *prometheus.Desc
+
+	firmwareBudget int
 }
 
-var state float64
+//batteryTestLayouts are the layouts battery test times are tried against when they are not a unix timestamp.
+var batteryTestLayouts = []string{
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	time.ANSIC,
+	time.UnixDate,
+}
+
+var (
+	state float64
+
+	firmwareTTLFlag     *time.Duration
+	firmwareLookupsFlag *int
+)
 
 func init() {
 	registerCollector("node_info", defaultEnabled, NewNodeStatusCollector)
+
+	//Drive firmware cache flags.
+	firmwareTTLFlagName := "collector.node_info.firmware-cache-ttl"
+	firmwareTTLFlagHelp := "How long the firmware of a drive missing from the node drive listing is cached for (default: 6h)."
+	firmwareTTLFlag = kingpin.Flag(firmwareTTLFlagName, firmwareTTLFlagHelp).Default("6h").Duration()
+	firmwareLookupsFlagName := "collector.node_info.firmware-lookups"
+	firmwareLookupsFlagHelp := "Maximum number of drive firmware lookups made against the cluster per scrape (default: 100)."
+	firmwareLookupsFlag = kingpin.Flag(firmwareLookupsFlagName, firmwareLookupsFlagHelp).Default("100").Int()
 }
 
 //NewNodeStatusCollector exposed various metrics and information about nodes.
 func NewNodeStatusCollector() (Collector, error) {
+	driveFirmware.SetTTL(*firmwareTTLFlag)
+
 	return &nodeStatusCollector{
+		firmwareBudget: *firmwareLookupsFlag,
 		nodeInfo: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "info"),
 			"Contains information about each node in labels. Always returns a 1.",
@@ -55,6 +98,61 @@ func NewNodeStatusCollector() (Collector, error) {
 			"Current state of the drive in a bay. 0 = HEALTHY/L3, 1 = STALLED, 2 = FW_UPDATE, 3 = SMARTFAILED, 4 = USED, 5 = PREPARING, 10 = NEW, 11 = EMPTY, 12 = REPLACE, 99 = UNKNOWN.",
 			[]string{"node", "node_id", "bay_num", "media_type", "model", "interaface_type", "dev_name", "state"}, ConstLabels,
 		),
+		nodeDriveInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "drive_info"),
+			"Contains information about the drive in a bay in labels. Always returns a 1.",
+			[]string{"node", "node_id", "bay_num", "dev_name", "model", "serial", "purpose", "firmware", "desired_firmware"}, ConstLabels,
+		),
+		nodeDriveFirmwareCurrent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "drive_firmware_current"),
+			"1 if the drive runs its desired firmware (or has none set), 0 if a firmware update is outstanding.",
+			[]string{"node", "node_id", "bay_num", "dev_name"}, ConstLabels,
+		),
+		nodeNvramBatteryVoltage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "nvram_battery_voltage"),
+			"Voltage of an NVRAM battery.",
+			[]string{"node", "node_id", "battery"}, ConstLabels,
+		),
+		nodeNvramBatteryInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "nvram_battery_info"),
+			"Contains the status of an NVRAM battery in labels. Always returns a 1.",
+			[]string{"node", "node_id", "battery", "status", "color"}, ConstLabels,
+		),
+		nodeNvramChargeStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "nvram_charge_status"),
+			"NVRAM battery charge status number as reported by the node, the charge_status label has its description.",
+			[]string{"node", "node_id", "charge_status"}, ConstLabels,
+		),
+		nodeBatteryNextTest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "battery_next_test_seconds"),
+			"Seconds until the next battery self-test. Negative if the test is overdue.",
+			[]string{"node", "node_id", "battery"}, ConstLabels,
+		),
+		nodeBatteryLastTest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "battery_last_test_timestamp"),
+			"Timestamp of the last battery self-test, the result label has its outcome.",
+			[]string{"node", "node_id", "battery", "result"}, ConstLabels,
+		),
+		nodeNvramPresentSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "nvram_present_size"),
+			"Size of the NVRAM installed in the node.",
+			[]string{"node", "node_id", "type"}, ConstLabels,
+		),
+		nodeNvramSupportedSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "nvram_supported_size"),
+			"Size of NVRAM the node supports.",
+			[]string{"node", "node_id", "type"}, ConstLabels,
+		),
+		nodeNvramSizeMismatch: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "nvram_size_mismatch"),
+			"1 if the installed NVRAM size or type does not match what the node supports, 0 if it does.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeDriveStateSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "drive_state_seconds"),
+			"Seconds the drive has been in its current state, counted from when the exporter first saw it in that state.",
+			[]string{"node", "node_id", "bay_num", "dev_name", "state"}, ConstLabels,
+		),
 	}, nil
 }
 
@@ -67,6 +165,10 @@ func (c *nodeStatusCollector) Update(ch chan<- prometheus.Metric) error {
 	if err != nil {
 		log.Warnf("Unable to update battery status. %s", err)
 	}
+	err = c.updateNvramStatus(ch, resp)
+	if err != nil {
+		log.Warnf("Unable to update nvram status. %s", err)
+	}
 	err = c.updatePowerSupplyStatus(ch, resp)
 	if err != nil {
 		log.Warnf("Unable to update power supply status. %s", err)
@@ -100,6 +202,83 @@ func (c *nodeStatusCollector) updateBatteryStatus(ch chan<- prometheus.Metric, n
 	return nil
 }
 
+func (c *nodeStatusCollector) updateNvramStatus(ch chan<- prometheus.Metric, nodeStatus isiclient.IsiNodesStatus) error {
+	now := time.Now()
+	for _, node := range nodeStatus.Nodes {
+		nodeID := fmt.Sprintf("%v", node.ID)
+		nodeLNN := fmt.Sprintf("%v", node.Lnn)
+		nvram := node.Nvram
+
+		for _, battery := range nvram.Batteries {
+			batteryID := fmt.Sprintf("%v", battery.ID)
+			ch <- prometheus.MustNewConstMetric(c.nodeNvramBatteryInfo, prometheus.GaugeValue, 1, nodeLNN, nodeID, batteryID, battery.Status, battery.Color)
+			voltage, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(battery.Voltage), "V"), 64)
+			if err != nil {
+				log.Debugf("Unable to parse voltage of battery %s on node %s: %s", batteryID, nodeLNN, battery.Voltage)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.nodeNvramBatteryVoltage, prometheus.GaugeValue, voltage, nodeLNN, nodeID, batteryID)
+		}
+		if nvram.Present {
+			ch <- prometheus.MustNewConstMetric(c.nodeNvramChargeStatus, prometheus.GaugeValue, nvram.ChargeStatusNumber, nodeLNN, nodeID, nvram.ChargeStatus)
+		}
+
+		//Battery self-tests are reported for up to two batteries.
+		if node.Batterystatus.Supported && node.Batterystatus.Present {
+			tests := []struct {
+				battery string
+				next    string
+				last    string
+				result  string
+			}{
+				{"1", node.Batterystatus.NextTestTime1, node.Batterystatus.LastTestTime1, node.Batterystatus.Result1},
+				{"2", node.Batterystatus.NextTestTime2, node.Batterystatus.LastTestTime2, node.Batterystatus.Result2},
+			}
+			for _, test := range tests {
+				if next, ok := parseBatteryTestTime(test.next); ok {
+					ch <- prometheus.MustNewConstMetric(c.nodeBatteryNextTest, prometheus.GaugeValue, next.Sub(now).Seconds(), nodeLNN, nodeID, test.battery)
+				}
+				if last, ok := parseBatteryTestTime(test.last); ok {
+					ch <- prometheus.MustNewConstMetric(c.nodeBatteryLastTest, prometheus.GaugeValue, float64(last.Unix()), nodeLNN, nodeID, test.battery, test.result)
+				}
+			}
+		}
+
+		if nvram.Supported {
+			ch <- prometheus.MustNewConstMetric(c.nodeNvramPresentSize, prometheus.GaugeValue, nvram.PresentSize, nodeLNN, nodeID, nvram.PresentType)
+			ch <- prometheus.MustNewConstMetric(c.nodeNvramSupportedSize, prometheus.GaugeValue, nvram.SupportedSize, nodeLNN, nodeID, nvram.SupportedType)
+			var mismatch float64
+			if !nvram.Present || nvram.PresentSize != nvram.SupportedSize || nvram.PresentType != nvram.SupportedType {
+				mismatch = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.nodeNvramSizeMismatch, prometheus.GaugeValue, mismatch, nodeLNN, nodeID)
+		}
+	}
+	return nil
+}
+
+//parseBatteryTestTime parses a battery test time, which is either a unix timestamp or a date read in the local time zone of the exporter.
+//ok is false if the time is not set or can not be parsed.
+func parseBatteryTestTime(value string) (t time.Time, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "N/A" {
+		return t, false
+	}
+	if epoch, err := strconv.ParseFloat(value, 64); err == nil {
+		if epoch <= 0 {
+			return t, false
+		}
+		return time.Unix(int64(epoch), 0), true
+	}
+	for _, layout := range batteryTestLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	log.Debugf("Unable to parse battery test time: %s", value)
+	return t, false
+}
+
 func (c *nodeStatusCollector) updatePowerSupplyStatus(ch chan<- prometheus.Metric, nodeStatus isiclient.IsiNodesStatus) error {
 	for _, node := range nodeStatus.Nodes {
 		nodeID := fmt.Sprintf("%v", node.ID)
@@ -124,6 +303,7 @@ func (c *nodeStatusCollector) updateDriveStatus(ch chan<- prometheus.Metric) err
 		log.Warnf("Unabled to collect drive status. %s", err)
 		return err
 	}
+	now := time.Now()
 	for _, node := range resp.Nodes {
 		nodeID := fmt.Sprintf("%v", node.ID)
 		nodeLNN := fmt.Sprintf("%v", node.Lnn)
@@ -154,8 +334,11 @@ func (c *nodeStatusCollector) updateDriveStatus(ch chan<- prometheus.Metric) err
 				state = 99
 			}
 			ch <- prometheus.MustNewConstMetric(c.nodeDriveState, prometheus.GaugeValue, state, nodeLNN, nodeID, bayID, drive.MediaType, drive.Model, drive.InterfaceType, devID, drive.UIState)
+			c.updateDriveDetails(ch, nodeLNN, nodeID, bayID, drive, now)
 		}
 	}
+	driveStates.Prune(now)
+	driveFirmware.Prune(now)
 	return nil
 }
 
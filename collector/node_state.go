@@ -0,0 +1,177 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+type nodeStateCollector struct {
+	nodeReadonly           *prometheus.Desc
+	nodeReadonlyEnabled    *prometheus.Desc
+	nodeSmartfailDead      *prometheus.Desc
+	nodeSmartfailDown      *prometheus.Desc
+	nodeSmartfailInCluster *prometheus.Desc
+	nodeSmartfailed        *prometheus.Desc
+	nodeShutdownReadonly   *prometheus.Desc
+	nodeServicelight       *prometheus.Desc
+	nodeUp                 *prometheus.Desc
+	clusterQuorum          *prometheus.Desc
+	clusterNodesUp         *prometheus.Desc
+	clusterNodesConfigured *prometheus.Desc
+}
+
+func init() {
+	registerCollector("node_state", defaultEnabled, NewNodeStateCollector)
+}
+
+//NewNodeStateCollector returns a new Collector exposing node read-only, smartfail and service light state and cluster quorum.
+func NewNodeStateCollector() (Collector, error) {
+	return &nodeStateCollector{
+		nodeReadonly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "readonly"),
+			"1 if the node is in read-only mode, 0 if not.",
+			[]string{"node", "node_id", "status"}, ConstLabels,
+		),
+		nodeReadonlyEnabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "readonly_enabled"),
+			"1 if read-only mode has been requested for the node, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeSmartfailDead: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "smartfail_dead"),
+			"1 if the node is dead, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeSmartfailDown: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "smartfail_down"),
+			"1 if the node is down, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeSmartfailInCluster: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "smartfail_in_cluster"),
+			"1 if the node is in the cluster, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeSmartfailed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "smartfailed"),
+			"1 if the node is smartfailed or being smartfailed, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeShutdownReadonly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "shutdown_readonly"),
+			"1 if the node is read-only because it is shutting down, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeServicelight: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "servicelight"),
+			"1 if the service light of the node is on, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		nodeUp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "up"),
+			"1 if the node is up and joined to the cluster, 0 if not.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		clusterQuorum: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clusterCollectorSubsystem, "has_quorum"),
+			"1 if the cluster has quorum, 0 if not.",
+			nil, ConstLabels,
+		),
+		clusterNodesUp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clusterCollectorSubsystem, "nodes_up"),
+			"Number of nodes that are up and joined to the cluster.",
+			nil, ConstLabels,
+		),
+		clusterNodesConfigured: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clusterCollectorSubsystem, "nodes_configured"),
+			"Number of nodes configured in the cluster.",
+			nil, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *nodeStateCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+	err := c.updateNodeStates(ch)
+	if err != nil {
+		log.Warnf("Unable to update node states: %s", err)
+		errCount++
+	}
+	err = c.updateMembership(ch)
+	if err != nil {
+		log.Warnf("Unable to update cluster membership: %s", err)
+		errCount++
+	}
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+//boolToFloat returns 1 for true and 0 for false.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (c *nodeStateCollector) updateNodeStates(ch chan<- prometheus.Metric) error {
+	resp, err := isiclient.GetNodesState(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	for _, node := range resp.Nodes {
+		nodeID := fmt.Sprintf("%v", node.ID)
+		nodeLNN := fmt.Sprintf("%v", node.Lnn)
+
+		if node.Readonly.Valid {
+			ch <- prometheus.MustNewConstMetric(c.nodeReadonly, prometheus.GaugeValue, boolToFloat(node.Readonly.Mode), nodeLNN, nodeID, node.Readonly.Status)
+			ch <- prometheus.MustNewConstMetric(c.nodeReadonlyEnabled, prometheus.GaugeValue, boolToFloat(node.Readonly.Enabled), nodeLNN, nodeID)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.nodeSmartfailDead, prometheus.GaugeValue, boolToFloat(node.Smartfail.Dead), nodeLNN, nodeID)
+		ch <- prometheus.MustNewConstMetric(c.nodeSmartfailDown, prometheus.GaugeValue, boolToFloat(node.Smartfail.Down), nodeLNN, nodeID)
+		ch <- prometheus.MustNewConstMetric(c.nodeSmartfailInCluster, prometheus.GaugeValue, boolToFloat(node.Smartfail.InCluster), nodeLNN, nodeID)
+		ch <- prometheus.MustNewConstMetric(c.nodeSmartfailed, prometheus.GaugeValue, boolToFloat(node.Smartfail.Smartfailed), nodeLNN, nodeID)
+		ch <- prometheus.MustNewConstMetric(c.nodeShutdownReadonly, prometheus.GaugeValue, boolToFloat(node.Smartfail.ShutdownReadonly), nodeLNN, nodeID)
+
+		if node.Servicelight.Valid && node.Servicelight.Supported {
+			ch <- prometheus.MustNewConstMetric(c.nodeServicelight, prometheus.GaugeValue, boolToFloat(node.Servicelight.Enabled), nodeLNN, nodeID)
+		}
+	}
+	return nil
+}
+
+func (c *nodeStateCollector) updateMembership(ch chan<- prometheus.Metric) error {
+	config, err := isiclient.GetClusterConfig(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	var up float64
+	for _, device := range config.Devices {
+		if device.IsUp {
+			up++
+		}
+		ch <- prometheus.MustNewConstMetric(c.nodeUp, prometheus.GaugeValue, boolToFloat(device.IsUp), fmt.Sprintf("%v", device.Lnn), fmt.Sprintf("%v", device.Devid))
+	}
+	ch <- prometheus.MustNewConstMetric(c.clusterNodesUp, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(c.clusterNodesConfigured, prometheus.GaugeValue, float64(len(config.Devices)))
+	ch <- prometheus.MustNewConstMetric(c.clusterQuorum, prometheus.GaugeValue, boolToFloat(config.HasQuorum))
+	return nil
+}
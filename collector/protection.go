@@ -0,0 +1,229 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+type protectionCollector struct {
+	policyInfo      *prometheus.Desc
+	belowSuggested  *prometheus.Desc
+	achievable      *prometheus.Desc
+	overheadRatio   *prometheus.Desc
+	overheadBytes   *prometheus.Desc
+	settingsInfo    *prometheus.Desc
+	vhsLimitDrives  *prometheus.Desc
+	vhsLimitPercent *prometheus.Desc
+	vhsDenyWrites   *prometheus.Desc
+	vhsHideSpare    *prometheus.Desc
+}
+
+const (
+	protectionSubSystem = "protection"
+	//maxStripeData is the most data units OneFS puts in a single protection stripe.
+	maxStripeData = 16
+)
+
+var (
+	mirrorPolicy = regexp.MustCompile(`^(\d+)x$`)
+	fecPolicy    = regexp.MustCompile(`^\+(\d+)(?:n|d:(\d+)n(?:(\d+)d)?)$`)
+)
+
+//protectionLevel is a parsed requested protection policy such as 3x, +2n or +3d:1n1d.
+type protectionLevel struct {
+	//Mirrors is the number of copies of a mirrored policy, 0 for forward error correction.
+	Mirrors int
+	//Drives is the number of drives that can fail without losing data.
+	Drives int
+	//Nodes is the number of nodes that can fail without losing data.
+	Nodes int
+	//PerNode is the number of protection units of a stripe written to every node.
+	PerNode int
+}
+
+func init() {
+	registerCollector("protection", defaultDisabled, NewProtectionCollector)
+}
+
+//NewProtectionCollector returns a new Collector exposing requested protection, estimated protection overhead and virtual hot spare settings.
+func NewProtectionCollector() (Collector, error) {
+	return &protectionCollector{
+		policyInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "policy_info"),
+			"Contains the requested and the suggested protection policy of the node pool in labels. Always returns a 1.",
+			[]string{"name", "requested", "suggested"}, ConstLabels,
+		),
+		belowSuggested: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "below_suggested"),
+			"1 if the requested protection of the node pool tolerates fewer failures than the suggested protection, 0 if not.",
+			[]string{"name"}, ConstLabels,
+		),
+		achievable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "achievable"),
+			"1 if the node pool has enough nodes to protect large files at the requested protection, 0 if they are mirrored instead.",
+			[]string{"name"}, ConstLabels,
+		),
+		overheadRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "overhead_ratio"),
+			"Estimated fraction of the used bytes of the node pool taken by protection, assuming files large enough to fill a stripe.",
+			[]string{"name"}, ConstLabels,
+		),
+		overheadBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "overhead_bytes"),
+			"Estimated number of used bytes of the node pool taken by protection, assuming files large enough to fill a stripe.",
+			[]string{"name"}, ConstLabels,
+		),
+		settingsInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "settings_info"),
+			"Contains the global SmartPools protection settings in labels. Always returns a 1.",
+			[]string{"automatically_manage_protection", "protect_directories_one_level_higher"}, ConstLabels,
+		),
+		vhsLimitDrives: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "virtual_hot_spare_limit_drives"),
+			"Number of drives worth of space reserved for virtual hot spare in every node pool.",
+			nil, ConstLabels,
+		),
+		vhsLimitPercent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "virtual_hot_spare_limit_percent"),
+			"Percentage of space reserved for virtual hot spare in every node pool.",
+			nil, ConstLabels,
+		),
+		vhsDenyWrites: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "virtual_hot_spare_deny_writes"),
+			"1 if writes to the virtual hot spare reservation are denied, 0 if not.",
+			nil, ConstLabels,
+		),
+		vhsHideSpare: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, protectionSubSystem, "virtual_hot_spare_hide_spare"),
+			"1 if the virtual hot spare reservation is subtracted from the reported capacity, 0 if not.",
+			nil, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *protectionCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+
+	settings, err := isiclient.GetStoragePoolSettings(IsiCluster.Client)
+	if err != nil {
+		errCount++
+	} else {
+		s := settings.Settings
+		ch <- prometheus.MustNewConstMetric(c.settingsInfo, prometheus.GaugeValue, 1,
+			s.AutomaticallyManageProtection, strconv.FormatBool(s.ProtectDirectoriesOneLevelHigher))
+		ch <- prometheus.MustNewConstMetric(c.vhsLimitDrives, prometheus.GaugeValue, s.VirtualHotSpareLimitDrives)
+		ch <- prometheus.MustNewConstMetric(c.vhsLimitPercent, prometheus.GaugeValue, s.VirtualHotSpareLimitPercent)
+		ch <- prometheus.MustNewConstMetric(c.vhsDenyWrites, prometheus.GaugeValue, boolToFloat(s.VirtualHotSpareDenyWrites))
+		ch <- prometheus.MustNewConstMetric(c.vhsHideSpare, prometheus.GaugeValue, boolToFloat(s.VirtualHotSpareHideSpare))
+	}
+
+	pools, err := isiclient.GetStoragePools(IsiCluster.Client)
+	if err != nil {
+		errCount++
+	} else {
+		for _, pool := range pools.Storagepools {
+			//Protection is requested and suggested per node pool, tiers only group them.
+			if pool.Type != "nodepool" {
+				continue
+			}
+			if err := c.updatePool(ch, pool); err != nil {
+				errCount++
+			}
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+func (c *protectionCollector) updatePool(ch chan<- prometheus.Metric, pool isiclient.IsiStoragePool) error {
+	requested, ok := parseProtection(pool.ProtectionPolicy)
+	if !ok {
+		log.Debugf("Unable to parse protection policy %q of node pool %s", pool.ProtectionPolicy, pool.Name)
+	} else {
+		ratio, achievable := requested.Overhead(len(pool.Lnns))
+		ch <- prometheus.MustNewConstMetric(c.achievable, prometheus.GaugeValue, boolToFloat(achievable), pool.Name)
+		ch <- prometheus.MustNewConstMetric(c.overheadRatio, prometheus.GaugeValue, ratio, pool.Name)
+		if pool.Usage.TotalBytes.Valid() && pool.Usage.FreeBytes.Valid() {
+			used := float64(pool.Usage.TotalBytes) - float64(pool.Usage.FreeBytes)
+			ch <- prometheus.MustNewConstMetric(c.overheadBytes, prometheus.GaugeValue, used*ratio, pool.Name)
+		}
+	}
+
+	resp, err := isiclient.GetSuggestedProtection(IsiCluster.Client, fmt.Sprintf("%v", pool.ID))
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.policyInfo, prometheus.GaugeValue, 1, pool.Name, pool.ProtectionPolicy, resp.SuggestedProtection)
+	if suggested, sok := parseProtection(resp.SuggestedProtection); ok && sok {
+		ch <- prometheus.MustNewConstMetric(c.belowSuggested, prometheus.GaugeValue, boolToFloat(requested.Below(suggested)), pool.Name)
+	}
+	return nil
+}
+
+//parseProtection parses a protection policy. ok is false for policies that are not a mirror or forward error correction level.
+func parseProtection(policy string) (protectionLevel, bool) {
+	if m := mirrorPolicy.FindStringSubmatch(policy); m != nil {
+		copies, _ := strconv.Atoi(m[1])
+		return protectionLevel{Mirrors: copies, Drives: copies - 1, Nodes: copies - 1, PerNode: 1}, copies > 0
+	}
+	m := fecPolicy.FindStringSubmatch(policy)
+	if m == nil {
+		return protectionLevel{}, false
+	}
+	drives, _ := strconv.Atoi(m[1])
+	level := protectionLevel{Drives: drives, Nodes: drives, PerNode: 1}
+	//+Nd:Mn spreads N units over M nodes, +Nd:Mn1d puts the extra drive on one of them.
+	if m[2] != "" {
+		level.Nodes, _ = strconv.Atoi(m[2])
+		extra, _ := strconv.Atoi(m[3])
+		if level.Nodes > 0 && drives-extra >= level.Nodes {
+			level.PerNode = (drives - extra) / level.Nodes
+		}
+	}
+	return level, drives > 0 && level.Nodes > 0
+}
+
+//Overhead returns the fraction of a full stripe taken by protection in a pool of the given number of nodes.
+//achievable is false if there are too few nodes for the level and OneFS falls back to mirroring.
+func (p protectionLevel) Overhead(nodes int) (float64, bool) {
+	if p.Mirrors > 0 {
+		return float64(p.Mirrors-1) / float64(p.Mirrors), true
+	}
+	width := nodes * p.PerNode
+	if width > maxStripeData+p.Drives {
+		width = maxStripeData + p.Drives
+	}
+	//A stripe needs more data than protection units, otherwise the file is mirrored Drives+1 times.
+	if width < 2*p.Drives+1 {
+		return float64(p.Drives) / float64(p.Drives+1), false
+	}
+	return float64(p.Drives) / float64(width), true
+}
+
+//Below reports whether p tolerates fewer node or drive failures than other.
+func (p protectionLevel) Below(other protectionLevel) bool {
+	if p.Nodes != other.Nodes {
+		return p.Nodes < other.Nodes
+	}
+	return p.Drives < other.Drives
+}
@@ -11,7 +11,6 @@ written permission of Adobe.
 package collector
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
@@ -41,13 +40,54 @@ type quotaCollector struct {
 	quotaThresholdSoftExceeded         *prometheus.Desc
 	quotaThresholdSoftLastExceeded     *prometheus.Desc
 	quotaCollectedNumber               *prometheus.Desc
+	quotaCollectionComplete            *prometheus.Desc
+	quotaMissingNumber                 *prometheus.Desc
+	quotaDuplicateNumber               *prometheus.Desc
+	quotaUsageRatio                    *prometheus.Desc
+	quotaRemainingBytes                *prometheus.Desc
+	quotaSoftExceededSeconds           *prometheus.Desc
+	quotaSoftGraceRemaining            *prometheus.Desc
+	quotaHardLimitEstimate             *prometheus.Desc
+	quotaExportedNumber                *prometheus.Desc
+	quotaReportGenerated               *prometheus.Desc
+	quotaLinked                        *prometheus.Desc
+	quotaPersonaInfo                   *prometheus.Desc
+	quotaNotificationMode              *prometheus.Desc
+	quotaNotificationRules             *prometheus.Desc
+	quotaDefaultDerived                *prometheus.Desc
+	filter                             *quotaFilter
+	filterZones                        []string
+	top                                int
+	topBy                              string
+	zones                              []isiclient.IsiZone
+	lookupBudget                       int
+	notificationBudget                 int
+	sampleTime                         time.Time
+	defaultNotifications               []isiclient.IsiQuotaNotification
+	defaultNotificationsFetched        bool
+	defaultNotificationsErr            error
 }
 
 var (
-	typeFlag     *string
-	exceededFlag *bool
-	rtoken       string
-	attempt      = int64(0)
+	typeFlag          *string
+	exceededFlag      *bool
+	retryFlag         *int
+	windowFlag        *time.Duration
+	includeFlag       *[]string
+	excludeFlag       *[]string
+	zoneFlag          *[]string
+	minUsageFlag      *float64
+	topFlag           *int
+	topByFlag         *string
+	resolveFlag       *string
+	ttlFlag           *time.Duration
+	lookupsFlag       *int
+	notifyTTLFlag     *time.Duration
+	notifyLookupsFlag *int
+	sourceFlag        *string
+
+	//quotaLabelNames are the labels every per quota metric has.
+	quotaLabelNames = []string{"id", "path", "name", "type", "persona_id", "persona_type", "zone"}
 )
 
 func init() {
@@ -62,11 +102,86 @@ func init() {
 	exceededFlagName := "collector.quota.exceeded"
 	exceededFlagHelp := "Only turn quotas that have exceeded one of more thresholds (default: false). Boolean of type (false, true)."
 	exceededFlag = kingpin.Flag(exceededFlagName, exceededFlagHelp).Default("false").Bool()
+
+	//Quota page retry flag.
+	retryFlagName := "collector.quota.retry"
+	retryFlagHelp := "Number of times a failed page of quotas is retried before the collection is reported incomplete (default: 3)."
+	retryFlag = kingpin.Flag(retryFlagName, retryFlagHelp).Default("3").Int()
+
+	//Quota usage forecast window flag.
+	windowFlagName := "collector.quota.forecast-window"
+	windowFlagHelp := "How long usage samples are kept to estimate the time until a quota reaches its hard limit (default: 24h)."
+	windowFlag = kingpin.Flag(windowFlagName, windowFlagHelp).Default("24h").Duration()
+
+	//Quota path filter flags.
+	includeFlagName := "collector.quota.include-path"
+	includeFlagHelp := "Only export quotas whose path matches this glob, or regex when prefixed with \"re:\". May be repeated."
+	includeFlag = kingpin.Flag(includeFlagName, includeFlagHelp).Strings()
+	excludeFlagName := "collector.quota.exclude-path"
+	excludeFlagHelp := "Do not export quotas whose path matches this glob, or regex when prefixed with \"re:\". May be repeated."
+	excludeFlag = kingpin.Flag(excludeFlagName, excludeFlagHelp).Strings()
+
+	//Quota access zone flag.
+	zoneFlagName := "collector.quota.zone"
+	zoneFlagHelp := "Only export quotas in this access zone, the zone with the longest base path containing the quota. May be repeated."
+	zoneFlag = kingpin.Flag(zoneFlagName, zoneFlagHelp).Strings()
+
+	//Quota minimum usage flag.
+	minUsageFlagName := "collector.quota.min-usage"
+	minUsageFlagHelp := "Only export quotas using at least this many bytes (default: 0)."
+	minUsageFlag = kingpin.Flag(minUsageFlagName, minUsageFlagHelp).Default("0").Float64()
+
+	//Quota top N flags.
+	topFlagName := "collector.quota.top"
+	topFlagHelp := "Only export the N quotas ranked highest by --collector.quota.top-by (default: 0, export all)."
+	topFlag = kingpin.Flag(topFlagName, topFlagHelp).Default("0").Int()
+	topByFlagName := "collector.quota.top-by"
+	topByFlagHelp := "Ranking used for --collector.quota.top. One of (usage, hard-percent)."
+	topByFlag = kingpin.Flag(topByFlagName, topByFlagHelp).Default("usage").Enum("usage", "hard-percent")
+
+	//Quota persona name resolution flags.
+	resolveFlagName := "collector.quota.resolve-names"
+	resolveFlagHelp := "How persona names are resolved. One of (api: ask the quota api on every page, cache: look up and cache names in the exporter, none: do not resolve names)."
+	resolveFlag = kingpin.Flag(resolveFlagName, resolveFlagHelp).Default("cache").Enum("api", "cache", "none")
+	ttlFlagName := "collector.quota.persona-cache-ttl"
+	ttlFlagHelp := "How long resolved persona names, and failures to resolve them, are cached for (default: 1h)."
+	ttlFlag = kingpin.Flag(ttlFlagName, ttlFlagHelp).Default("1h").Duration()
+	lookupsFlagName := "collector.quota.persona-lookups"
+	lookupsFlagHelp := "Maximum number of persona lookups made against the cluster per scrape when names are cached (default: 1000)."
+	lookupsFlag = kingpin.Flag(lookupsFlagName, lookupsFlagHelp).Default("1000").Int()
+
+	//Quota custom notification cache flags.
+	notifyTTLFlagName := "collector.quota.notification-cache-ttl"
+	notifyTTLFlagHelp := "How long the custom notification rules of a quota are cached for (default: 1h)."
+	notifyTTLFlag = kingpin.Flag(notifyTTLFlagName, notifyTTLFlagHelp).Default("1h").Duration()
+	notifyLookupsFlagName := "collector.quota.notification-lookups"
+	notifyLookupsFlagHelp := "Maximum number of quotas whose custom notification rules are fetched from the cluster per scrape (default: 100)."
+	notifyLookupsFlag = kingpin.Flag(notifyLookupsFlagName, notifyLookupsFlagHelp).Default("100").Int()
+
+	//Quota data source flag.
+	sourceFlagName := "collector.quota.source"
+	sourceFlagHelp := "Where quota data is read from. One of (live: page the live quota data, report: read the latest scheduled quota report)."
+	sourceFlag = kingpin.Flag(sourceFlagName, sourceFlagHelp).Default("live").Enum("live", "report")
 }
 
 //NewQuotaCollector returns a new Collector exposing node health information.
 func NewQuotaCollector() (Collector, error) {
+	quotaHistory.SetWindow(*windowFlag)
+	personas.SetTTL(*ttlFlag)
+	customNotifications.SetTTL(*notifyTTLFlag)
+
+	filter, err := newQuotaFilter(*includeFlag, *excludeFlag, *minUsageFlag)
+	if err != nil {
+		return nil, err
+	}
+
 	return &quotaCollector{
+		filter:             filter,
+		filterZones:        *zoneFlag,
+		top:                *topFlag,
+		topBy:              *topByFlag,
+		lookupBudget:       *lookupsFlag,
+		notificationBudget: *notifyLookupsFlag,
 		quotaIterationCollectionTime: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "api_collection_duration"),
 			"Returns the amount of time it took to collect an iteration of quotas from the api.",
@@ -75,87 +190,162 @@ func NewQuotaCollector() (Collector, error) {
 		quotaContainer: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "container"),
 			"1 if quota is a container quota, 0 if not.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaEnforced: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "enforced"),
 			"1 if quota is enforced, 2 if quota is an advisory quota.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaIncludeSnapshots: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "include_snapshots"),
 			"1 if quota includes snapshots in usage, 0 if not.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaUsageLogical: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "usage_logical"),
 			"Apparent bytes used by governed data.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaUsageInodes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "usage_inodes"),
 			"Number of inodes (filesystem entities) used by governed data.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaUsagePhysical: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "usage_physical"),
 			"Bytes used for governed data and filesystem overhead.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdAdvisory: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_advisory"),
 			"Usage bytes at which notifications will be sent but writes will not be denied.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdAdvisoryExceeded: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_advisory_exceeded"),
 			"1 if the advisory threshold has been hit.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdAdvisoryLastExceeded: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_advisory_last_exceeded"),
 			"Timestamp of when threshold was last exceeded.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdSoft: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_soft"),
 			"Usage bytes at which notifications will be sent and soft grace time will be started.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdSoftExceeded: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_soft_exceeded"),
 			"1 if the soft threshold has been hit.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdSoftGrace: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_soft_grace"),
 			"Time in seconds after which the soft threshold has been hit before writes will be denied.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdSoftLastExceeded: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_soft_last_exceeded"),
 			"Timestamp of when threshold was last exceeded.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdHard: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_hard"),
 			"Usage bytes at which further writes will be denied.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdHardExceeded: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_hard_exceeded"),
 			"True if the hard threshold has been hit.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaThresholdHardLastExceeded: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "threshold_hard_last_exceeded"),
 			"Timestamp of when threshold was last exceeded.",
-			[]string{"id", "path", "name", "type"}, ConstLabels,
+			quotaLabelNames, ConstLabels,
 		),
 		quotaCollectedNumber: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "collected_total"),
 			"Number of quotas collected by the quota collector.",
-			[]string{"attempt"}, ConstLabels,
+			nil, ConstLabels,
+		),
+		quotaCollectionComplete: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "collection_complete"),
+			"1 if every page of quotas was collected and the count matches the quota summary, 0 if not.",
+			nil, ConstLabels,
+		),
+		quotaMissingNumber: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "collection_missing"),
+			"Number of quotas reported by the quota summary that were not collected.",
+			nil, ConstLabels,
+		),
+		quotaDuplicateNumber: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "collection_duplicates"),
+			"Number of quotas returned more than once by the api and skipped.",
+			nil, ConstLabels,
+		),
+		quotaExportedNumber: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "exported_total"),
+			"Number of collected quotas that passed the path, zone, usage and top N filters.",
+			nil, ConstLabels,
+		),
+		quotaUsageRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "usage_ratio"),
+			"Usage as a ratio of the threshold from 0.0 - 1.0+. Physical usage is used if thresholds include overhead, logical if not.",
+			quotaLabelsWith("threshold"), ConstLabels,
+		),
+		quotaRemainingBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "remaining_bytes"),
+			"Bytes left before the threshold is reached. Negative if the threshold has been exceeded.",
+			quotaLabelsWith("threshold"), ConstLabels,
+		),
+		quotaSoftExceededSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "soft_exceeded_seconds"),
+			"Seconds since the soft threshold was exceeded.",
+			quotaLabelNames, ConstLabels,
+		),
+		quotaSoftGraceRemaining: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "soft_grace_remaining_seconds"),
+			"Seconds left of the soft grace period before writes will be denied.",
+			quotaLabelNames, ConstLabels,
+		),
+		quotaReportGenerated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "report_generated_timestamp"),
+			"Timestamp of when the quota report the quota metrics were read from was generated.",
+			nil, ConstLabels,
+		),
+		quotaPersonaInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "persona_info"),
+			"Contains the uid, gid and sid of the user or group the quota applies to in labels, empty where unknown. Always returns a 1.",
+			[]string{"id", "persona_id", "persona_type", "uid", "gid", "sid"}, ConstLabels,
+		),
+		quotaLinked: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "linked"),
+			"1 if the quota is linked to and controlled by a default-user or default-group quota, 0 if not.",
+			quotaLabelNames, ConstLabels,
+		),
+		quotaNotificationMode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "notification_mode"),
+			"Always 1, the mode label tells whether the quota uses the default, custom or no (disabled) notification rules.",
+			quotaLabelsWith("mode"), ConstLabels,
+		),
+		quotaNotificationRules: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "notification_rules"),
+			"Number of notification rules for the threshold that raise an alert or send an email. 0 if nobody is notified.",
+			quotaLabelsWith("threshold"), ConstLabels,
+		),
+		quotaDefaultDerived: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "default_derived_total"),
+			"Number of linked user or group quotas derived from the default-user or default-group quota. Only exported when all quota types are collected.",
+			[]string{"id", "path", "type"}, ConstLabels,
+		),
+		quotaHardLimitEstimate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, quotaCollectorSubsystem, "hard_limit_estimate_seconds"),
+			"Estimated seconds until usage reaches the hard threshold, from a linear fit of usage over the forecast window.",
+			quotaLabelNames, ConstLabels,
 		),
 	}, nil
 }
@@ -163,161 +353,284 @@ func NewQuotaCollector() (Collector, error) {
 func (c *quotaCollector) Update(ch chan<- prometheus.Metric) error {
 	log.Debugf("Collecting quota type(s): %s", *typeFlag)
 	log.Debugf("Collected only exceeded quotas: %v", *exceededFlag)
+	log.Debugf("Collecting quotas from: %s", *sourceFlag)
 
-	// Keep going until there is no resume token
-	var collectedCount int64
-	var collectNumber int
-	var err error
-	rtoken = "unset"
-	var quotas isiclient.IsiQuotas
-	for rtoken != "" {
-		// Collect a time and counter for each iteration of quotas
-		collectNumber++
-		begin := time.Now()
-		attempt++
+	switch *typeFlag {
+	case "directory", "user", "group", "default-user", "default-group", "all":
+	default:
+		return fmt.Errorf("Unknown quota type: %s", *typeFlag)
+	}
 
-		//Ask for first set of quotas
-		quotas, err = c.getQuotas()
+	//Resolve access zones to their base paths on every scrape as they can change.
+	zones, err := isiclient.GetZones(IsiCluster.Client)
+	if err != nil {
+		if len(c.filterZones) > 0 {
+			return err
+		}
+		log.Warnf("Unable to label quotas with their access zone: %s", err)
+	}
+	c.zones = zones.Zones
+	if len(c.filterZones) > 0 {
+		err = c.filter.setZones(c.filterZones, zones)
 		if err != nil {
-			log.Warnf("Unable to collect quotas for type: %s", *typeFlag)
+			return err
 		}
+	}
 
-		//Calculate the time it took to gather this iteration of quotas
-		duration := time.Since(begin)
+	//Usage is sampled now for live data, or when the report was generated.
+	c.sampleTime = time.Now()
+	var reportID string
+	if *sourceFlag == "report" {
+		report, err := getLatestQuotaReport()
+		if err != nil {
+			return err
+		}
+		reportID = report.ID
+		c.sampleTime = time.Unix(int64(report.Time), 0)
+		log.Debugf("Collecting quotas from report %s", reportID)
+		ch <- prometheus.MustNewConstMetric(c.quotaReportGenerated, prometheus.GaugeValue, report.Time)
+	}
 
-		//Grab the resume token if there is one. If there is not one it will be a empty string.
-		rtoken = quotas.Resume
+	// Keep going until there is no resume token. Failed pages are retried by the iterator.
+	var (
+		collectedCount int64
+		exportedCount  int64
+		duplicates     int64
+		seen           = make(map[string]bool)
+		top            *quotaTopN
+		links          = newQuotaLinks()
+	)
+	if c.top > 0 {
+		top = newQuotaTopN(c.top, c.topBy)
+	}
+	it := isiclient.NewQuotasIterator(IsiCluster.Client, *exceededFlag, *typeFlag, *resolveFlag == "api", reportID)
+	it.Retries = *retryFlag
+	for collectNumber := 1; ; collectNumber++ {
+		// Collect a time for each iteration of quotas
+		begin := time.Now()
 
-		//Add the number of collected quotas to the total.
-		collectedCount += int64(len(quotas.Quotas))
+		var quotas isiclient.IsiQuotas
+		if !it.Next(&quotas) {
+			break
+		}
+
+		//Calculate the time it took to gather this iteration of quotas
+		duration := time.Since(begin)
 
 		//Create a new metric of the amount of time it took to collect this iteration of quotas.
 		ch <- prometheus.MustNewConstMetric(c.quotaIterationCollectionTime, prometheus.GaugeValue, duration.Seconds(), fmt.Sprintf("%v", collectNumber))
 
-		//Range over all quotas in this iteration.
-
+		//Range over all quotas in this iteration, skipping any the api has already handed us.
 		for _, quota := range quotas.Quotas {
-			// Get username for the quota
-			var name string
-			var nerr error
-			if quota.Type != "directory" {
-				name, nerr = c.getQuotaUserName(quota)
-				if nerr != nil {
-					log.Infof("Unabled to get a name for quota: %s", nerr)
-				}
-			} else {
-				name = quota.Path
+			if seen[quota.ID] {
+				duplicates++
+				continue
 			}
+			seen[quota.ID] = true
+			collectedCount++
+			links.Add(quota)
 
-			//Gather meta-data metrics
-			nerr = c.updateMetaData(ch, quota, name)
-			if nerr != nil {
-				log.Warnf("Unable to update meta data forquota: %s", name)
+			if !c.filter.Match(quota) {
+				continue
 			}
-
-			//Gather usage metrics
-			nerr = c.updateUsage(ch, quota, name)
-			if nerr != nil {
-				log.Warnf("Unable to update usage for quota: %s", name)
+			//With top N the quotas can only be exported once every page has been seen.
+			if top != nil {
+				top.Offer(quota)
+				continue
 			}
+			exportedCount++
+			c.updateQuota(ch, quota)
+		}
+	}
+	if top != nil {
+		for _, quota := range top.Quotas() {
+			exportedCount++
+			c.updateQuota(ch, quota)
+		}
+	}
 
-			//Gather threshold metrics
-			nerr = c.updateThresholds(ch, quota, name)
-			if nerr != nil {
-				log.Warnf("Unable to update usage for quota: %s", name)
-			}
+	quotaHistory.Prune(c.sampleTime)
+	customNotifications.Prune(time.Now())
+
+	//Derived quotas are only all seen when every quota type is collected.
+	if *typeFlag == "all" && !*exceededFlag {
+		for _, quota := range links.defaults {
+			ch <- prometheus.MustNewConstMetric(c.quotaDefaultDerived, prometheus.GaugeValue, float64(links.Derived(quota)), quota.ID, quota.Path, quota.Type)
 		}
 	}
 
-	ch <- prometheus.MustNewConstMetric(c.quotaCollectedNumber, prometheus.GaugeValue, float64(collectedCount), string(attempt))
+	complete := float64(1)
+	if err := it.Err(); err != nil {
+		log.Warnf("Unable to collect all quotas for type %s after %v pages: %s", *typeFlag, it.Pages(), err)
+		complete = 0
+	}
 
-	if IsiCluster.QuotaOnly {
-		if (collectedCount != IsiCluster.Quotas.Count) && (!*exceededFlag) && (*typeFlag == "all") {
-			log.Warnf("Collected %v quotas of a total of %v", collectedCount, IsiCluster.Quotas.Count)
-			if attempt <= IsiCluster.Quotas.Retry {
-				log.Infof("Recursive quota collection attempt number %v", attempt+1)
-				err = c.Update(ch)
-			} else {
-				mesg := fmt.Sprintf("eexceded retry attempts to collect quota information: attempt %v/%v", attempt, IsiCluster.Quotas.Count)
-				err = errors.New(mesg)
-				return err
+	ch <- prometheus.MustNewConstMetric(c.quotaCollectedNumber, prometheus.GaugeValue, float64(collectedCount))
+	ch <- prometheus.MustNewConstMetric(c.quotaDuplicateNumber, prometheus.GaugeValue, float64(duplicates))
+	ch <- prometheus.MustNewConstMetric(c.quotaExportedNumber, prometheus.GaugeValue, float64(exportedCount))
+
+	//The summary can only tell us how many quotas to expect when we are not filtering on exceeded quotas.
+	//It describes the live quota data so it is of no use for a report either.
+	if !*exceededFlag && reportID == "" {
+		expected, err := c.getExpectedCount()
+		if err != nil {
+			log.Warnf("Unable to get expected number of quotas: %s", err)
+		} else {
+			var missing int64
+			if collectedCount < expected {
+				missing = expected - collectedCount
+				complete = 0
 			}
-		} else if (collectedCount == 0) && (IsiCluster.Quotas.Count > 0) {
-			log.Warnf("Collected %v quotas of a total of %v", collectedCount, IsiCluster.Quotas.Count)
-			if attempt <= IsiCluster.Quotas.Retry {
-				log.Infof("Recursive quota collection attempt number %v", attempt+1)
-				err = c.Update(ch)
+			ch <- prometheus.MustNewConstMetric(c.quotaMissingNumber, prometheus.GaugeValue, float64(missing))
+			if missing > 0 {
+				log.Warnf("Collected %v quotas of a total of %v", collectedCount, expected)
 			} else {
-				mesg := fmt.Sprintf("eexceded retry attempts to collect quota information: attempt %v/%v", attempt, IsiCluster.Quotas.Count)
-				err = errors.New(mesg)
-				return err
+				log.Debugf("Collected %v quotas of a total of %v", collectedCount, expected)
 			}
-		} else {
-			log.Infof("Collected %v quotas of a total of %v", collectedCount, IsiCluster.Quotas.Count)
 		}
 	} else {
 		log.Debugf("Collected %v quotas.", collectedCount)
 	}
+	ch <- prometheus.MustNewConstMetric(c.quotaCollectionComplete, prometheus.GaugeValue, complete)
 
-	return err
+	return nil
 }
 
-func (c *quotaCollector) getQuotas() (isiclient.IsiQuotas, error) {
-	//Check to see what type of quota is being collected.
-	var collectErr error
-	var quotas isiclient.IsiQuotas
-	if rtoken != "" && rtoken != "unset" {
-		quotas, collectErr = isiclient.GetQuotasWithResume(IsiCluster.Client, rtoken)
-	} else {
-		switch *typeFlag {
-		case "directory":
-			quotas, collectErr = isiclient.GetQuotasOfType(IsiCluster.Client, *exceededFlag, *typeFlag)
-		case "user":
-			quotas, collectErr = isiclient.GetQuotasOfType(IsiCluster.Client, *exceededFlag, *typeFlag)
-		case "group":
-			quotas, collectErr = isiclient.GetQuotasOfType(IsiCluster.Client, *exceededFlag, *typeFlag)
-		case "default-user":
-			quotas, collectErr = isiclient.GetQuotasOfType(IsiCluster.Client, *exceededFlag, *typeFlag)
-		case "default-group":
-			quotas, collectErr = isiclient.GetQuotasOfType(IsiCluster.Client, *exceededFlag, *typeFlag)
-		case "all":
-			quotas, collectErr = isiclient.GetAllQuotas(IsiCluster.Client, *exceededFlag)
-		default:
-			mesg := fmt.Sprintf("Unknown quota type: %s", *typeFlag)
-			collectErr = errors.New(mesg)
-			return quotas, collectErr
+//getLatestQuotaReport returns the most recently generated scheduled quota report.
+//Reports are only listed once they have been written so the newest one is complete.
+func getLatestQuotaReport() (isiclient.IsiQuotaReport, error) {
+	var (
+		latest isiclient.IsiQuotaReport
+		found  bool
+	)
+	it := isiclient.NewQuotaReportsIterator(IsiCluster.Client, "scheduled")
+	var reports isiclient.IsiQuotaReports
+	for it.Next(&reports) {
+		for _, report := range reports.Reports {
+			if !found || report.Time > latest.Time {
+				latest = report
+				found = true
+			}
 		}
 	}
-
-	return quotas, collectErr
+	if err := it.Err(); err != nil {
+		return latest, err
+	}
+	if !found {
+		return latest, fmt.Errorf("No scheduled quota report found")
+	}
+	return latest, nil
 }
 
-func (c *quotaCollector) getQuotaUserName(q isiclient.IsiQuota) (string, error) {
-	var (
-		name string
-	)
+//getExpectedCount returns the number of quotas of the collected type according to the quota summary.
+func (c *quotaCollector) getExpectedCount() (int64, error) {
+	summary, err := isiclient.GetQuotaSummary(IsiCluster.Client)
+	if err != nil {
+		return 0, err
+	}
+	IsiCluster.Quotas.Count = int64(summary.Count)
+
+	switch *typeFlag {
+	case "directory":
+		return int64(summary.DirectoryQuotasCount), nil
+	case "user":
+		return int64(summary.UserQuotasCount), nil
+	case "group":
+		return int64(summary.GroupQuotasCount), nil
+	case "default-user":
+		return int64(summary.DefaultUserQuotasCount), nil
+	case "default-group":
+		return int64(summary.DefaultGroupQuotasCount), nil
+	default:
+		return int64(summary.Count), nil
+	}
+}
 
-	//Try and unmarshal the persona interface.
-	if q.Persona != nil {
-		per, ok := q.Persona.(map[string]interface{})
-		if !ok {
-			err := errors.New("unable to unmarshal the persona interface{}")
-			return "", err
-		}
-		val, ok := per["name"]
-		if !ok {
-			name = q.Path
-		} else {
-			name = val.(string)
+//updateQuota emits every metric for a single quota.
+func (c *quotaCollector) updateQuota(ch chan<- prometheus.Metric, quota isiclient.IsiQuota) {
+	// Get username for the quota
+	var name string
+	if quota.Type != "directory" {
+		persona := c.getQuotaPersona(quota)
+		name = persona.Name
+		if quota.Persona != nil {
+			ch <- prometheus.MustNewConstMetric(c.quotaPersonaInfo, prometheus.GaugeValue, 1, quota.ID, quota.Persona.ID, quota.Persona.Type,
+				persona.UID, persona.GID, persona.SID)
 		}
 	} else {
+		name = quota.Path
+	}
+	lv := quotaLabelValues(quota, name, zoneOfPath(c.zones, quota.Path))
+
+	//Gather meta-data metrics
+	err := c.updateMetaData(ch, quota, lv)
+	if err != nil {
+		log.Warnf("Unable to update meta data for quota: %s", quota.ID)
+	}
+
+	//Gather usage metrics
+	err = c.updateUsage(ch, quota, lv)
+	if err != nil {
+		log.Warnf("Unable to update usage for quota: %s", quota.ID)
+	}
+
+	//Gather threshold metrics
+	err = c.updateThresholds(ch, quota, lv)
+	if err != nil {
+		log.Warnf("Unable to update usage for quota: %s", quota.ID)
+	}
+
+	//Gather notification and link metrics
+	err = c.updateNotifications(ch, quota, lv)
+	if err != nil {
+		log.Warnf("Unable to update notifications for quota: %s", quota.ID)
+	}
+
+	//Gather metrics derived from usage and thresholds
+	err = c.updateUtilization(ch, quota, lv)
+	if err != nil {
+		log.Warnf("Unable to update utilization for quota: %s", quota.ID)
+	}
+}
+
+//getQuotaPersona returns the user or group a quota applies to. The name and ids are empty where unknown.
+func (c *quotaCollector) getQuotaPersona(q isiclient.IsiQuota) isiclient.IsiResolvedPersona {
+	if q.Persona == nil {
 		log.Debugf("Persona is nil: %v", q.Path)
-		name = q.Path
+		return isiclient.IsiResolvedPersona{}
+	}
+
+	var resolved isiclient.IsiResolvedPersona
+	switch *resolveFlag {
+	case "api":
+		resolved.Name = q.Persona.Name
+	case "cache":
+		if q.Persona.Name != "" {
+			resolved.Name = q.Persona.Name
+		} else {
+			resolved = personas.Lookup(*q.Persona, &c.lookupBudget)
+		}
+	}
+	return resolved.Merge(*q.Persona)
+}
+
+//quotaLabelsWith returns quotaLabelNames followed by an extra label.
+func quotaLabelsWith(extra string) []string {
+	labels := make([]string, 0, len(quotaLabelNames)+1)
+	return append(append(labels, quotaLabelNames...), extra)
+}
+
+//quotaLabelValues returns the values for quotaLabelNames.
+func quotaLabelValues(q isiclient.IsiQuota, name string, zone string) []string {
+	var personaID, personaType string
+	if q.Persona != nil {
+		personaID = q.Persona.ID
+		personaType = q.Persona.Type
 	}
-	return name, nil
+	return []string{q.ID, q.Path, name, q.Type, personaID, personaType, zone}
 }
 
-func (c *quotaCollector) updateMetaData(ch chan<- prometheus.Metric, q isiclient.IsiQuota, n string) error {
+func (c *quotaCollector) updateMetaData(ch chan<- prometheus.Metric, q isiclient.IsiQuota, lv []string) error {
 	var (
 		container       float64
 		enforced        float64
@@ -329,7 +642,7 @@ func (c *quotaCollector) updateMetaData(ch chan<- prometheus.Metric, q isiclient
 	} else {
 		container = 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.quotaContainer, prometheus.GaugeValue, container, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaContainer, prometheus.GaugeValue, container, lv...)
 
 	//Gather enforcement status
 	if q.Enforced {
@@ -337,7 +650,7 @@ func (c *quotaCollector) updateMetaData(ch chan<- prometheus.Metric, q isiclient
 	} else {
 		enforced = 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.quotaEnforced, prometheus.GaugeValue, enforced, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaEnforced, prometheus.GaugeValue, enforced, lv...)
 
 	//Gather include_snapshots
 	if q.IncludeSnapshots {
@@ -345,20 +658,20 @@ func (c *quotaCollector) updateMetaData(ch chan<- prometheus.Metric, q isiclient
 	} else {
 		includeSnapshot = 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.quotaIncludeSnapshots, prometheus.GaugeValue, includeSnapshot, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaIncludeSnapshots, prometheus.GaugeValue, includeSnapshot, lv...)
 
 	return nil
 }
 
-func (c *quotaCollector) updateUsage(ch chan<- prometheus.Metric, q isiclient.IsiQuota, n string) error {
+func (c *quotaCollector) updateUsage(ch chan<- prometheus.Metric, q isiclient.IsiQuota, lv []string) error {
 	// Update logical
-	ch <- prometheus.MustNewConstMetric(c.quotaUsageLogical, prometheus.GaugeValue, q.Usage.Logical, q.ID, q.Path, n, q.Type)
-	ch <- prometheus.MustNewConstMetric(c.quotaUsageInodes, prometheus.GaugeValue, q.Usage.Inodes, q.ID, q.Path, n, q.Type)
-	ch <- prometheus.MustNewConstMetric(c.quotaUsagePhysical, prometheus.GaugeValue, q.Usage.Physical, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaUsageLogical, prometheus.GaugeValue, q.Usage.Logical, lv...)
+	ch <- prometheus.MustNewConstMetric(c.quotaUsageInodes, prometheus.GaugeValue, q.Usage.Inodes, lv...)
+	ch <- prometheus.MustNewConstMetric(c.quotaUsagePhysical, prometheus.GaugeValue, q.Usage.Physical, lv...)
 	return nil
 }
 
-func (c *quotaCollector) updateThresholds(ch chan<- prometheus.Metric, q isiclient.IsiQuota, n string) error {
+func (c *quotaCollector) updateThresholds(ch chan<- prometheus.Metric, q isiclient.IsiQuota, lv []string) error {
 	//gather advisory thresholds
 	var (
 		ae  float64
@@ -369,7 +682,7 @@ func (c *quotaCollector) updateThresholds(ch chan<- prometheus.Metric, q isiclie
 		sle float64
 		ok  bool
 	)
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdAdvisory, prometheus.GaugeValue, q.Thresholds.Advisory, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdAdvisory, prometheus.GaugeValue, q.Thresholds.Advisory, lv...)
 	if q.Thresholds.AdvisoryExceeded {
 		ae = 1
 		ale, ok = q.Thresholds.AdvisoryLastExceeded.(float64)
@@ -381,11 +694,11 @@ func (c *quotaCollector) updateThresholds(ch chan<- prometheus.Metric, q isiclie
 		ae = 0
 		ale = 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdAdvisoryExceeded, prometheus.GaugeValue, ae, q.ID, q.Path, n, q.Type)
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdAdvisoryLastExceeded, prometheus.GaugeValue, ale, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdAdvisoryExceeded, prometheus.GaugeValue, ae, lv...)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdAdvisoryLastExceeded, prometheus.GaugeValue, ale, lv...)
 
 	//gather hard thresholds
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdHard, prometheus.GaugeValue, q.Thresholds.Hard, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdHard, prometheus.GaugeValue, q.Thresholds.Hard, lv...)
 	if q.Thresholds.HardExceeded {
 		he = 1
 		hle, ok = q.Thresholds.HardLastExceeded.(float64)
@@ -397,11 +710,11 @@ func (c *quotaCollector) updateThresholds(ch chan<- prometheus.Metric, q isiclie
 		he = 0
 		hle = 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdHardExceeded, prometheus.GaugeValue, he, q.ID, q.Path, n, q.Type)
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdHardLastExceeded, prometheus.GaugeValue, hle, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdHardExceeded, prometheus.GaugeValue, he, lv...)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdHardLastExceeded, prometheus.GaugeValue, hle, lv...)
 
 	//gather soft thresholds
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoft, prometheus.GaugeValue, q.Thresholds.Soft, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoft, prometheus.GaugeValue, q.Thresholds.Soft, lv...)
 	if q.Thresholds.SoftExceeded {
 		se = 1
 		sle, ok = q.Thresholds.SoftLastExceeded.(float64)
@@ -413,8 +726,60 @@ func (c *quotaCollector) updateThresholds(ch chan<- prometheus.Metric, q isiclie
 		se = 0
 		sle = 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoftExceeded, prometheus.GaugeValue, se, q.ID, q.Path, n, q.Type)
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoftLastExceeded, prometheus.GaugeValue, sle, q.ID, q.Path, n, q.Type)
-	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoftGrace, prometheus.GaugeValue, q.Thresholds.SoftGrace, q.ID, q.Path, n, q.Type)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoftExceeded, prometheus.GaugeValue, se, lv...)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoftLastExceeded, prometheus.GaugeValue, sle, lv...)
+	ch <- prometheus.MustNewConstMetric(c.quotaThresholdSoftGrace, prometheus.GaugeValue, q.Thresholds.SoftGrace, lv...)
+	return nil
+}
+
+func (c *quotaCollector) updateUtilization(ch chan<- prometheus.Metric, q isiclient.IsiQuota, lv []string) error {
+	now := time.Now()
+
+	usage := quotaUsage(q)
+
+	thresholds := map[string]float64{
+		"advisory": q.Thresholds.Advisory,
+		"soft":     q.Thresholds.Soft,
+		"hard":     q.Thresholds.Hard,
+	}
+	for threshold, bytes := range thresholds {
+		//A threshold of 0 is not set.
+		if bytes <= 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.quotaUsageRatio, prometheus.GaugeValue, usage/bytes, append(lv, threshold)...)
+		ch <- prometheus.MustNewConstMetric(c.quotaRemainingBytes, prometheus.GaugeValue, bytes-usage, append(lv, threshold)...)
+	}
+
+	//Time left before the soft threshold turns into denied writes.
+	if q.Thresholds.SoftExceeded {
+		sle, ok := q.Thresholds.SoftLastExceeded.(float64)
+		if !ok {
+			log.Warnf("Unable to convert soft last exceeded timestamp to float: %s", q.Thresholds.SoftLastExceeded)
+		} else {
+			exceeded := now.Sub(time.Unix(int64(sle), 0)).Seconds()
+			remaining := q.Thresholds.SoftGrace - exceeded
+			if remaining < 0 {
+				remaining = 0
+			}
+			ch <- prometheus.MustNewConstMetric(c.quotaSoftExceededSeconds, prometheus.GaugeValue, exceeded, lv...)
+			ch <- prometheus.MustNewConstMetric(c.quotaSoftGraceRemaining, prometheus.GaugeValue, remaining, lv...)
+		}
+	}
+
+	//Estimate when the hard threshold will be hit from the usage trend.
+	samples := quotaHistory.Record(q.ID, c.sampleTime, usage)
+	if q.Thresholds.Hard <= 0 {
+		return nil
+	}
+	if usage >= q.Thresholds.Hard {
+		ch <- prometheus.MustNewConstMetric(c.quotaHardLimitEstimate, prometheus.GaugeValue, 0, lv...)
+		return nil
+	}
+	slope, ok := usageSlope(samples)
+	if ok && slope > 0 {
+		eta := (q.Thresholds.Hard - usage) / slope
+		ch <- prometheus.MustNewConstMetric(c.quotaHardLimitEstimate, prometheus.GaugeValue, eta, lv...)
+	}
 	return nil
 }
@@ -0,0 +1,245 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+package collector
+
+import (
+	"container/heap"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+)
+
+//pathMatcher matches a quota path against either a glob or a regular expression.
+type pathMatcher struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+//newPathMatcher compiles a pattern. Patterns prefixed with "re:" are regular expressions, everything else is a glob.
+func newPathMatcher(pattern string) (pathMatcher, error) {
+	if strings.HasPrefix(pattern, "re:") {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "re:"))
+		if err != nil {
+			return pathMatcher{}, fmt.Errorf("Invalid quota path regex %s: %s", pattern, err)
+		}
+		return pathMatcher{regex: re}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return pathMatcher{}, fmt.Errorf("Invalid quota path glob %s: %s", pattern, err)
+	}
+	return pathMatcher{glob: pattern}, nil
+}
+
+func (m pathMatcher) Match(p string) bool {
+	if m.regex != nil {
+		return m.regex.MatchString(p)
+	}
+	ok, _ := path.Match(m.glob, p)
+	return ok
+}
+
+//quotaFilter decides which quotas are exported.
+type quotaFilter struct {
+	include   []pathMatcher
+	exclude   []pathMatcher
+	zoneNames map[string]bool
+	zones     []isiclient.IsiZone
+	minUsage  float64
+}
+
+//newQuotaFilter builds a filter from the include and exclude patterns.
+func newQuotaFilter(include []string, exclude []string, minUsage float64) (*quotaFilter, error) {
+	f := &quotaFilter{minUsage: minUsage}
+	for _, pattern := range include {
+		m, err := newPathMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, pattern := range exclude {
+		m, err := newPathMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return f, nil
+}
+
+//setZones restricts the filter to quotas in the named access zones.
+//A quota is in the zone with the longest base path containing it, the same zone it is labelled with.
+func (f *quotaFilter) setZones(names []string, zones isiclient.IsiZones) error {
+	f.zoneNames = make(map[string]bool)
+	f.zones = zones.Zones
+	for _, name := range names {
+		found := false
+		for _, zone := range zones.Zones {
+			if zone.Name == name {
+				f.zoneNames[name] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown access zone: %s", name)
+		}
+	}
+	return nil
+}
+
+//Match returns true if the quota passes every configured filter.
+func (f *quotaFilter) Match(q isiclient.IsiQuota) bool {
+	if quotaUsage(q) < f.minUsage {
+		return false
+	}
+	if len(f.zoneNames) > 0 && !f.zoneNames[zoneOfPath(f.zones, q.Path)] {
+		return false
+	}
+	for _, m := range f.exclude {
+		if m.Match(q.Path) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, m := range f.include {
+		if m.Match(q.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+//Configure overrides the quota filter flags with the quota.* url parameters of the scrape.
+//Every parameter replaces the flag of the same name, the path and zone parameters may be repeated and are cleared by an empty value.
+func (c *quotaCollector) Configure(params url.Values) error {
+	include, exclude, minUsage := *includeFlag, *excludeFlag, *minUsageFlag
+	if v, ok := params["quota.include-path"]; ok {
+		include = nonEmpty(v)
+	}
+	if v, ok := params["quota.exclude-path"]; ok {
+		exclude = nonEmpty(v)
+	}
+	if v := params.Get("quota.min-usage"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid quota.min-usage %s: %s", v, err)
+		}
+		minUsage = n
+	}
+	filter, err := newQuotaFilter(include, exclude, minUsage)
+	if err != nil {
+		return err
+	}
+	c.filter = filter
+
+	if v, ok := params["quota.zone"]; ok {
+		c.filterZones = nonEmpty(v)
+	}
+	if v := params.Get("quota.top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("Invalid quota.top %s, must be 0 or more", v)
+		}
+		c.top = n
+	}
+	if v := params.Get("quota.top-by"); v != "" {
+		if v != "usage" && v != "hard-percent" {
+			return fmt.Errorf("Invalid quota.top-by %s, must be one of (usage, hard-percent)", v)
+		}
+		c.topBy = v
+	}
+	return nil
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+//quotaUsage returns the usage that thresholds are compared against.
+//Physical usage is used when thresholds include overhead, logical if not.
+func quotaUsage(q isiclient.IsiQuota) float64 {
+	if q.ThresholdsIncludeOverhead {
+		return q.Usage.Physical
+	}
+	return q.Usage.Logical
+}
+
+//quotaScore ranks a quota for top N selection.
+func quotaScore(q isiclient.IsiQuota, by string) float64 {
+	if by == "hard-percent" {
+		if q.Thresholds.Hard <= 0 {
+			return 0
+		}
+		return quotaUsage(q) / q.Thresholds.Hard
+	}
+	return quotaUsage(q)
+}
+
+type scoredQuota struct {
+	quota isiclient.IsiQuota
+	score float64
+}
+
+//quotaTopN keeps the N highest scoring quotas seen so far in a min heap.
+type quotaTopN struct {
+	n      int
+	by     string
+	quotas []scoredQuota
+}
+
+func newQuotaTopN(n int, by string) *quotaTopN {
+	return &quotaTopN{n: n, by: by}
+}
+
+func (t *quotaTopN) Len() int           { return len(t.quotas) }
+func (t *quotaTopN) Less(i, j int) bool { return t.quotas[i].score < t.quotas[j].score }
+func (t *quotaTopN) Swap(i, j int)      { t.quotas[i], t.quotas[j] = t.quotas[j], t.quotas[i] }
+func (t *quotaTopN) Push(x interface{}) { t.quotas = append(t.quotas, x.(scoredQuota)) }
+func (t *quotaTopN) Pop() (x interface{}) {
+	x, t.quotas = t.quotas[len(t.quotas)-1], t.quotas[:len(t.quotas)-1]
+	return x
+}
+
+//Offer adds a quota if it is among the N highest scoring quotas.
+func (t *quotaTopN) Offer(q isiclient.IsiQuota) {
+	sq := scoredQuota{quota: q, score: quotaScore(q, t.by)}
+	if len(t.quotas) < t.n {
+		heap.Push(t, sq)
+		return
+	}
+	if sq.score > t.quotas[0].score {
+		t.quotas[0] = sq
+		heap.Fix(t, 0)
+	}
+}
+
+//Quotas returns the kept quotas.
+func (t *quotaTopN) Quotas() []isiclient.IsiQuota {
+	out := make([]isiclient.IsiQuota, 0, len(t.quotas))
+	for _, sq := range t.quotas {
+		out = append(out, sq.quota)
+	}
+	return out
+}
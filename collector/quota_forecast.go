@@ -0,0 +1,117 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+//quotaMaxSamples bounds the memory used per quota, samples are spread evenly across the window.
+const quotaMaxSamples = 48
+
+//usageSample is a single observation of quota usage.
+type usageSample struct {
+	Time  time.Time
+	Bytes float64
+}
+
+//quotaUsageHistory keeps usage samples per quota id across scrapes.
+//A new collector is created for every scrape so the history has to live at the package level.
+type quotaUsageHistory struct {
+	mu      sync.Mutex
+	window  time.Duration
+	samples map[string][]usageSample
+}
+
+var quotaHistory = &quotaUsageHistory{
+	samples: make(map[string][]usageSample),
+}
+
+//SetWindow sets how long samples are kept for.
+func (h *quotaUsageHistory) SetWindow(window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.window = window
+}
+
+//Record adds a usage sample for a quota and drops samples that have aged out of the window.
+func (h *quotaUsageHistory) Record(id string, now time.Time, bytes float64) []usageSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current := usageSample{Time: now, Bytes: bytes}
+	samples := h.trim(h.samples[id], now)
+
+	//Only keep the sample if enough time has passed since the last kept one.
+	interval := h.window / quotaMaxSamples
+	if len(samples) == 0 || now.Sub(samples[len(samples)-1].Time) >= interval {
+		samples = append(samples, current)
+	}
+	h.samples[id] = samples
+
+	//Hand back a copy that always ends with the current sample so callers can use it without holding the lock.
+	out := make([]usageSample, len(samples), len(samples)+1)
+	copy(out, samples)
+	if out[len(out)-1] != current {
+		out = append(out, current)
+	}
+	return out
+}
+
+//Prune forgets every quota that has not been sampled within the window.
+func (h *quotaUsageHistory) Prune(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, samples := range h.samples {
+		samples = h.trim(samples, now)
+		if len(samples) == 0 {
+			delete(h.samples, id)
+		} else {
+			h.samples[id] = samples
+		}
+	}
+}
+
+func (h *quotaUsageHistory) trim(samples []usageSample, now time.Time) []usageSample {
+	idx := 0
+	for idx < len(samples) && now.Sub(samples[idx].Time) > h.window {
+		idx++
+	}
+	return samples[idx:]
+}
+
+//usageSlope returns the least squares growth rate of usage in bytes per second.
+//ok is false if there are not enough samples spread over time to fit a line.
+func usageSlope(samples []usageSample) (slope float64, ok bool) {
+	n := float64(len(samples))
+	if n < 2 {
+		return 0, false
+	}
+
+	//Use seconds relative to the first sample to keep the numbers small.
+	origin := samples[0].Time
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Time.Sub(origin).Seconds()
+		sumX += x
+		sumY += s.Bytes
+		sumXY += x * s.Bytes
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
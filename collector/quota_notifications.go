@@ -0,0 +1,180 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+//notificationEntry is the cached custom notification rules of a quota.
+type notificationEntry struct {
+	Rules   []isiclient.IsiQuotaNotification
+	Expires time.Time
+}
+
+//notificationCache caches the custom notification rules of quotas by quota id across scrapes.
+type notificationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]notificationEntry
+}
+
+var customNotifications = &notificationCache{
+	entries: make(map[string]notificationEntry),
+}
+
+//SetTTL sets how long fetched rules are kept for.
+func (n *notificationCache) SetTTL(ttl time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ttl = ttl
+}
+
+//Get returns the cached rules of a quota. ok is false if there is no unexpired entry.
+func (n *notificationCache) Get(id string, now time.Time) ([]isiclient.IsiQuotaNotification, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	entry, ok := n.entries[id]
+	if !ok || now.After(entry.Expires) {
+		return nil, false
+	}
+	return entry.Rules, true
+}
+
+//Set caches the rules of a quota.
+func (n *notificationCache) Set(id string, rules []isiclient.IsiQuotaNotification, now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.entries[id] = notificationEntry{Rules: rules, Expires: now.Add(n.ttl)}
+}
+
+//Prune drops every expired entry so deleted quotas do not stay cached.
+func (n *notificationCache) Prune(now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for id, entry := range n.entries {
+		if now.After(entry.Expires) {
+			delete(n.entries, id)
+		}
+	}
+}
+
+//quotaLinks counts the quotas derived from each default-user and default-group quota.
+//Derived quotas are linked user or group quotas on the same path as their default quota.
+type quotaLinks struct {
+	defaults []isiclient.IsiQuota
+	derived  map[string]int
+}
+
+func newQuotaLinks() *quotaLinks {
+	return &quotaLinks{derived: make(map[string]int)}
+}
+
+//Add records a collected quota.
+func (l *quotaLinks) Add(q isiclient.IsiQuota) {
+	switch q.Type {
+	case "default-user", "default-group":
+		l.defaults = append(l.defaults, q)
+	case "user", "group":
+		if q.Linked {
+			l.derived["default-"+q.Type+":"+q.Path]++
+		}
+	}
+}
+
+//Derived returns the number of quotas derived from a default quota.
+func (l *quotaLinks) Derived(q isiclient.IsiQuota) int {
+	return l.derived[q.Type+":"+q.Path]
+}
+
+//notificationRules returns the notification rules that apply to a quota.
+//ok is false if the rules could not be fetched, or the custom rules are not cached and the lookup budget is spent.
+func (c *quotaCollector) notificationRules(q isiclient.IsiQuota) (rules []isiclient.IsiQuotaNotification, ok bool) {
+	switch q.Notifications {
+	case "disabled":
+		return nil, true
+	case "custom":
+		now := time.Now()
+		if rules, ok := customNotifications.Get(q.ID, now); ok {
+			return rules, true
+		}
+		if c.notificationBudget <= 0 {
+			return nil, false
+		}
+		c.notificationBudget--
+		resp, err := isiclient.GetQuotaNotifications(IsiCluster.Client, q.ID)
+		if err != nil {
+			return nil, false
+		}
+		customNotifications.Set(q.ID, resp.Notifications, now)
+		return resp.Notifications, true
+	default:
+		//The default rules are the same for every quota so only fetch them once per scrape, even if that fails.
+		if !c.defaultNotificationsFetched {
+			c.defaultNotificationsFetched = true
+			resp, err := isiclient.GetQuotaDefaultNotifications(IsiCluster.Client)
+			if err != nil {
+				log.Warnf("Unable to collect default quota notification rules: %s", err)
+				c.defaultNotificationsErr = err
+				return nil, false
+			}
+			c.defaultNotifications = resp.Notifications
+		}
+		if c.defaultNotificationsErr != nil {
+			return nil, false
+		}
+		return c.defaultNotifications, true
+	}
+}
+
+func (c *quotaCollector) updateNotifications(ch chan<- prometheus.Metric, q isiclient.IsiQuota, lv []string) error {
+	var linked float64
+	if q.Linked {
+		linked = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.quotaLinked, prometheus.GaugeValue, linked, lv...)
+
+	mode := q.Notifications
+	if mode == "" {
+		mode = "default"
+	}
+	ch <- prometheus.MustNewConstMetric(c.quotaNotificationMode, prometheus.GaugeValue, 1, append(lv, mode)...)
+
+	rules, ok := c.notificationRules(q)
+	if !ok {
+		return nil
+	}
+	//Count the rules that notify anyone for every threshold that is set, a 0 means nobody hears about it.
+	thresholds := map[string]float64{
+		"advisory": q.Thresholds.Advisory,
+		"soft":     q.Thresholds.Soft,
+		"hard":     q.Thresholds.Hard,
+	}
+	for threshold, bytes := range thresholds {
+		if bytes <= 0 {
+			continue
+		}
+		var count float64
+		for _, rule := range rules {
+			if rule.Threshold == threshold && rule.Notifies() {
+				count++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.quotaNotificationRules, prometheus.GaugeValue, count, append(lv, threshold)...)
+	}
+	return nil
+}
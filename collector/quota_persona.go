@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/common/log"
+)
+
+//personaEntry is a cached persona lookup. Failed lookups are cached too so unresolvable ids are not retried every scrape.
+type personaEntry struct {
+	Persona isiclient.IsiResolvedPersona
+	Expires time.Time
+}
+
+//personaCache caches resolved personas by persona id across scrapes.
+type personaCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]personaEntry
+}
+
+var personas = &personaCache{
+	entries: make(map[string]personaEntry),
+}
+
+//SetTTL sets how long a looked up persona is kept for.
+func (p *personaCache) SetTTL(ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ttl = ttl
+}
+
+//Get returns the cached persona for a persona id. ok is false if there is no unexpired entry.
+func (p *personaCache) Get(id string, now time.Time) (isiclient.IsiResolvedPersona, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[id]
+	if !ok {
+		return isiclient.IsiResolvedPersona{}, false
+	}
+	if now.After(entry.Expires) {
+		delete(p.entries, id)
+		return isiclient.IsiResolvedPersona{}, false
+	}
+	return entry.Persona, true
+}
+
+//Set caches the resolved persona for a persona id.
+func (p *personaCache) Set(id string, persona isiclient.IsiResolvedPersona, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries[id] = personaEntry{Persona: persona, Expires: now.Add(p.ttl)}
+}
+
+//Lookup returns a persona from the cache, asking the cluster on a miss.
+//budget is decremented for every lookup made against the cluster, once it is spent misses return an empty persona.
+func (p *personaCache) Lookup(persona isiclient.IsiPersona, budget *int) isiclient.IsiResolvedPersona {
+	now := time.Now()
+	if resolved, ok := p.Get(persona.ID, now); ok {
+		return resolved
+	}
+	if *budget <= 0 {
+		return isiclient.IsiResolvedPersona{}
+	}
+	*budget--
+
+	resolved, err := isiclient.ResolvePersona(IsiCluster.Client, persona)
+	if err != nil {
+		log.Debugf("Unable to resolve persona %s: %s", persona.ID, err)
+	}
+	p.Set(persona.ID, resolved, now)
+	return resolved
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+type sensorsCollector struct {
+	nodeSensor     *prometheus.Desc
+	cpuOvertemp    *prometheus.Desc
+	cpuSpeedLimit  *prometheus.Desc
+	sensorFailures *prometheus.Desc
+	sensorStat     *prometheus.Desc
+}
+
+const (
+	//sensorStatPrefix is the prefix of the stats engine keys with hardware sensor readings.
+	sensorStatPrefix = "node.sensor."
+	//sensorStatBatch is the most sensor keys queried in a single stats engine call, keeping the request url short.
+	sensorStatBatch = 50
+)
+
+//sensorKeyCache remembers the sensor stats keys of the cluster so they are only listed once.
+type sensorKeyCache struct {
+	mu    sync.Mutex
+	keys  []string
+	units map[string]string
+}
+
+var sensorKeys = &sensorKeyCache{}
+
+//Get returns the sensor stats keys and their units, listing them from the cluster until that succeeds once.
+func (s *sensorKeyCache) Get() ([]string, map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys != nil {
+		return s.keys, s.units, nil
+	}
+
+	keys := []string{}
+	units := make(map[string]string)
+	it := isiclient.NewStatsKeysIterator(IsiCluster.Client)
+	var page isiclient.IsiStatsKeys
+	for it.Next(&page) {
+		for _, key := range page.Keys {
+			if strings.HasPrefix(key.Key, sensorStatPrefix) {
+				keys = append(keys, key.Key)
+				units[key.Key] = key.Units
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(keys)
+	s.keys, s.units = keys, units
+	return s.keys, s.units, nil
+}
+
+func init() {
+	registerCollector("sensors", defaultEnabled, NewSensorsCollector)
+}
+
+//NewSensorsCollector returns a new Collector exposing node hardware sensors and cpu throttling.
+func NewSensorsCollector() (Collector, error) {
+	return &sensorsCollector{
+		nodeSensor: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "sensor"),
+			"Current reading of a node hardware sensor (temperature, fan speed, voltage, power) in the units of the units label.",
+			[]string{"node", "node_id", "group", "sensor", "description", "units"}, ConstLabels,
+		),
+		sensorFailures: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "sensor_unreadable"),
+			"Number of sensors of the node whose reading is not a number (N/A, failed, etc.).",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		cpuOvertemp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "cpu_overtemp"),
+			"1 if the node reports a cpu over temperature condition, 0 if not.",
+			[]string{"node", "node_id", "overtemp"}, ConstLabels,
+		),
+		cpuSpeedLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "cpu_speed_limit"),
+			"Percentage of full speed the node cpus are limited to, less than 100 when throttled.",
+			[]string{"node", "node_id"}, ConstLabels,
+		),
+		sensorStat: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nodeCollectorSubsystem, "sensor_stat"),
+			"Current value of a node.sensor.* stats engine key, in the units of the units label.",
+			[]string{"node", "node_id", "key", "units"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *sensorsCollector) Update(ch chan<- prometheus.Metric) error {
+	var errCount int64
+	lnns, err := c.updateSensors(ch)
+	if err != nil {
+		log.Warnf("Unable to update node sensors: %s", err)
+		errCount++
+	}
+	err = c.updateSensorStats(ch, lnns)
+	if err != nil {
+		log.Warnf("Unable to update node sensor stats: %s", err)
+		errCount++
+	}
+	err = c.updateCPU(ch)
+	if err != nil {
+		log.Warnf("Unable to update cpu throttling: %s", err)
+		errCount++
+	}
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+//updateSensors reports the sensors of every node and returns the LNN of every node id.
+func (c *sensorsCollector) updateSensors(ch chan<- prometheus.Metric) (map[string]string, error) {
+	lnns := make(map[string]string)
+	resp, err := isiclient.GetNodesSensors(IsiCluster.Client)
+	if err != nil {
+		return lnns, err
+	}
+	for _, node := range resp.Nodes {
+		nodeID := fmt.Sprintf("%v", node.ID)
+		nodeLNN := fmt.Sprintf("%v", node.Lnn)
+		lnns[nodeID] = nodeLNN
+		var unreadable float64
+		for _, group := range node.Sensors {
+			for _, sensor := range group.Values {
+				val, err := strconv.ParseFloat(strings.TrimSpace(sensor.Value), 64)
+				if err != nil {
+					log.Debugf("Unable to parse sensor %s on node %s: %s", sensor.Name, nodeLNN, sensor.Value)
+					unreadable++
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(c.nodeSensor, prometheus.GaugeValue, val, nodeLNN, nodeID, group.Name, sensor.Name, sensor.Desc, sensor.Units)
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.sensorFailures, prometheus.GaugeValue, unreadable, nodeLNN, nodeID)
+	}
+	return lnns, nil
+}
+
+//updateSensorStats reports every node.sensor.* stats key of every node.
+//The keys are queried in batches, the call metrics of a batch are labelled with its first key.
+func (c *sensorsCollector) updateSensorStats(ch chan<- prometheus.Metric, lnns map[string]string) error {
+	keys, units, err := sensorKeys.Get()
+	if err != nil {
+		return err
+	}
+
+	var errCount int64
+	for start := 0; start < len(keys); start += sensorStatBatch {
+		end := start + sensorStatBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		begin := time.Now()
+		resp, err := isiclient.QueryStatsEngineSingleVal(IsiCluster.Client, strings.Join(batch, ","))
+		duration := time.Since(begin)
+		ch <- prometheus.MustNewConstMetric(statsEngineCallDuration, prometheus.GaugeValue, duration.Seconds(), batch[0])
+		if err != nil {
+			log.Warnf("Unable to collect sensor stats keys %s to %s: %s", batch[0], batch[len(batch)-1], err)
+			ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 1, batch[0])
+			errCount++
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 0, batch[0])
+		for _, stat := range resp.Stats {
+			if stat.Error != nil {
+				continue
+			}
+			nodeID := fmt.Sprintf("%v", stat.Devid)
+			ch <- prometheus.MustNewConstMetric(c.sensorStat, prometheus.GaugeValue, stat.Value, lnns[nodeID], nodeID, stat.Key, units[stat.Key])
+		}
+	}
+
+	if errCount != 0 {
+		return fmt.Errorf("There where %v errors", errCount)
+	}
+	return nil
+}
+
+func (c *sensorsCollector) updateCPU(ch chan<- prometheus.Metric) error {
+	resp, err := isiclient.GetNodesStatus(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	for _, node := range resp.Nodes {
+		nodeID := fmt.Sprintf("%v", node.ID)
+		nodeLNN := fmt.Sprintf("%v", node.Lnn)
+
+		var overtemp float64
+		switch strings.ToLower(strings.TrimSpace(node.CPU.Overtemp)) {
+		case "", "0", "no", "false", "none", "n/a":
+			overtemp = 0
+		default:
+			overtemp = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.cpuOvertemp, prometheus.GaugeValue, overtemp, nodeLNN, nodeID, node.CPU.Overtemp)
+
+		limit, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(node.CPU.SpeedLimit), "%"), 64)
+		if err != nil {
+			log.Debugf("Unable to parse cpu speed limit on node %s: %s", nodeLNN, node.CPU.SpeedLimit)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.cpuSpeedLimit, prometheus.GaugeValue, limit, nodeLNN, nodeID)
+	}
+	return nil
+}
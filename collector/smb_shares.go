@@ -8,19 +8,40 @@ it. If you have received this file from a source other than Adobe,
 then your use, modification, or distribution of it requires the prior
 written permission of Adobe.
 */
+
 package collector
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type smbSharesCollector struct {
-	sharesCount *prometheus.Desc
+	sharesCount      *prometheus.Desc
+	shareInfo        *prometheus.Desc
+	sharePermissions *prometheus.Desc
+	sessionsCount    *prometheus.Desc
+	nodeSessions     *prometheus.Desc
+	userSessions     *prometheus.Desc
+	openfilesCount   *prometheus.Desc
+	pathOpenfiles    *prometheus.Desc
 }
 
+var smbOpenfilesTopFlag *int
+
 func init() {
 	registerCollector("smb_shares", defaultEnabled, NewSmbSharesCollector)
+
+	//SMB open files top N flag.
+	smbOpenfilesTopFlagName := "collector.smb_shares.openfiles-top"
+	smbOpenfilesTopFlagHelp := "Export who has files open for the N paths with the most open files (default: 10, 0 does not collect open files)."
+	smbOpenfilesTopFlag = kingpin.Flag(smbOpenfilesTopFlagName, smbOpenfilesTopFlagHelp).Default("10").Int()
 }
 
 //NewSmbSharesCollector exposed various metrics and information about nodes.
@@ -31,6 +52,41 @@ func NewSmbSharesCollector() (Collector, error) {
 			"Total number of SMB shares on a cluster.",
 			nil, ConstLabels,
 		),
+		shareInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "share_info"),
+			"Always 1, the labels describe the configuration of the SMB share.",
+			[]string{"name", "zone", "path", "access_based_enumeration", "continuously_available"}, ConstLabels,
+		),
+		sharePermissions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "share_permissions"),
+			"Number of permission entries on the SMB share.",
+			[]string{"name", "zone"}, ConstLabels,
+		),
+		sessionsCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "sessions_total"),
+			"Total number of SMB sessions open on the cluster.",
+			nil, ConstLabels,
+		),
+		nodeSessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "node_sessions"),
+			"Number of SMB clients connected to the node.",
+			[]string{"node"}, ConstLabels,
+		),
+		userSessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "user_sessions"),
+			"Number of SMB sessions open by the user.",
+			[]string{"user"}, ConstLabels,
+		),
+		openfilesCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "openfiles_total"),
+			"Total number of files open over SMB on the cluster.",
+			nil, ConstLabels,
+		),
+		pathOpenfiles: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "smb", "path_openfiles"),
+			"Number of times the user has the file open, for the paths with the most open files.",
+			[]string{"path", "user"}, ConstLabels,
+		),
 	}, nil
 }
 
@@ -41,5 +97,136 @@ func (c *smbSharesCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	ch <- prometheus.MustNewConstMetric(c.sharesCount, prometheus.GaugeValue, resp.Summary.Count)
-	return err
+
+	//Shares are listed per access zone.
+	zones, err := isiclient.GetZones(IsiCluster.Client)
+	if err != nil {
+		return err
+	}
+	var errCount int64
+	for _, zone := range zones.Zones {
+		err = c.updateShares(ch, zone.Name)
+		if err != nil {
+			log.Warnf("Unable to collect smb shares of zone %s: %s", zone.Name, err)
+			errCount++
+		}
+	}
+
+	err = c.updateSessions(ch)
+	if err != nil {
+		log.Warnf("Unable to collect smb sessions: %s", err)
+		errCount++
+	}
+
+	if *smbOpenfilesTopFlag > 0 {
+		err = c.updateOpenfiles(ch)
+		if err != nil {
+			log.Warnf("Unable to collect smb open files: %s", err)
+			errCount++
+		}
+	}
+
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+func (c *smbSharesCollector) updateShares(ch chan<- prometheus.Metric, zone string) error {
+	it := isiclient.NewSmbSharesIterator(IsiCluster.Client, zone)
+	var page isiclient.IsiSmbShares
+	for it.Next(&page) {
+		for _, share := range page.Shares {
+			ch <- prometheus.MustNewConstMetric(c.shareInfo, prometheus.GaugeValue, 1, share.Name, zone, share.Path,
+				fmt.Sprintf("%v", share.AccessBasedEnumeration), fmt.Sprintf("%v", share.ContinuouslyAvailable))
+			ch <- prometheus.MustNewConstMetric(c.sharePermissions, prometheus.GaugeValue, float64(len(share.Permissions)), share.Name, zone)
+		}
+	}
+	return it.Err()
+}
+
+func (c *smbSharesCollector) updateSessions(ch chan<- prometheus.Metric) error {
+	var total float64
+	users := make(map[string]float64)
+	it := isiclient.NewSmbSessionsIterator(IsiCluster.Client)
+	var page isiclient.IsiSmbSessions
+	for it.Next(&page) {
+		for _, session := range page.Sessions {
+			total++
+			users[session.User]++
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.sessionsCount, prometheus.GaugeValue, total)
+	for user, count := range users {
+		ch <- prometheus.MustNewConstMetric(c.userSessions, prometheus.GaugeValue, count, user)
+	}
+
+	//Sessions do not say which node they are on, the stats engine counts connected clients per node.
+	const statKey = "node.clientstats.connected.smb"
+	begin := time.Now()
+	resp, err := isiclient.QueryStatsEngineSingleVal(IsiCluster.Client, statKey)
+	duration := time.Since(begin)
+	ch <- prometheus.MustNewConstMetric(statsEngineCallDuration, prometheus.GaugeValue, duration.Seconds(), statKey)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 1, statKey)
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(statsEngineCallFailure, prometheus.GaugeValue, 0, statKey)
+	for _, stat := range resp.Stats {
+		ch <- prometheus.MustNewConstMetric(c.nodeSessions, prometheus.GaugeValue, stat.Value, fmt.Sprintf("%v", stat.Devid))
+	}
+	return nil
+}
+
+func (c *smbSharesCollector) updateOpenfiles(ch chan<- prometheus.Metric) error {
+	var total float64
+	paths := make(map[string]map[string]float64)
+	it := isiclient.NewSmbOpenfilesIterator(IsiCluster.Client)
+	var page isiclient.IsiSmbOpenfiles
+	for it.Next(&page) {
+		for _, file := range page.Openfiles {
+			total++
+			if paths[file.File] == nil {
+				paths[file.File] = make(map[string]float64)
+			}
+			paths[file.File][file.User]++
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.openfilesCount, prometheus.GaugeValue, total)
+
+	//Rank paths by how often they are open and only export the top N.
+	type pathCount struct {
+		path  string
+		count float64
+	}
+	ranked := make([]pathCount, 0, len(paths))
+	for path, users := range paths {
+		var count float64
+		for _, n := range users {
+			count += n
+		}
+		ranked = append(ranked, pathCount{path: path, count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].count != ranked[j].count {
+			return ranked[i].count > ranked[j].count
+		}
+		return ranked[i].path < ranked[j].path
+	})
+	if len(ranked) > *smbOpenfilesTopFlag {
+		ranked = ranked[:*smbOpenfilesTopFlag]
+	}
+	for _, pc := range ranked {
+		for user, count := range paths[pc.path] {
+			ch <- prometheus.MustNewConstMetric(c.pathOpenfiles, prometheus.GaugeValue, count, pc.path, user)
+		}
+	}
+	return nil
 }
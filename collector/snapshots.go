@@ -11,12 +11,17 @@ written permission of Adobe.
 package collector
 
 import (
+	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type snapshotsCollector struct {
@@ -26,44 +31,50 @@ type snapshotsCollector struct {
 	snapshotsActiveSize    *prometheus.Desc
 	snapshotsDeletingCount *prometheus.Desc
 	snapshotsDeletingSize  *prometheus.Desc
-	snapshots7DayCount     *prometheus.Desc
-	snapshots15DayCount    *prometheus.Desc
-	snapshots30DayCount    *prometheus.Desc
-	snapshots60DayCount    *prometheus.Desc
-	snapshots90DayCount    *prometheus.Desc
+	snapshotsAgeDays       *prometheus.Desc
+	snapshotsAgeSize       *prometheus.Desc
+	ageBuckets             []float64
+	pathPrefixes           []string
 }
 
+var (
+	snapshotAgeBucketsFlag *string
+	snapshotPathPrefixFlag *string
+)
+
 func init() {
 	registerCollector("snapshots", defaultEnabled, NewSnapshotsCollector)
+
+	//Snapshot age bucket flag.
+	ageBucketsFlagName := "collector.snapshots.age-buckets"
+	ageBucketsFlagHelp := "Comma separated list of snapshot age bucket boundaries in days (default: 7,15,30,60,90)."
+	snapshotAgeBucketsFlag = kingpin.Flag(ageBucketsFlagName, ageBucketsFlagHelp).Default("7,15,30,60,90").String()
+
+	//Snapshot path prefix flag.
+	pathPrefixFlagName := "collector.snapshots.path-prefixes"
+	pathPrefixFlagHelp := "Comma separated list of path prefixes to partition snapshot ages by (default: none). Snapshots matching no prefix are reported as \"other\"."
+	snapshotPathPrefixFlag = kingpin.Flag(pathPrefixFlagName, pathPrefixFlagHelp).Default("").String()
 }
 
 //NewSnapshotsCollector returns a new Collector exposing sync IQ policy information.
 func NewSnapshotsCollector() (Collector, error) {
+	buckets, err := parseAgeBuckets(*snapshotAgeBucketsFlag)
+	if err != nil {
+		return nil, err
+	}
+
 	return &snapshotsCollector{
-		snapshots7DayCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "snapshots", "7_day_count"),
-			"Number of snapshots older than 7 days.",
-			nil, ConstLabels,
-		),
-		snapshots15DayCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "snapshots", "15_day_count"),
-			"Number of snapshots older than 15 days.",
-			nil, ConstLabels,
+		ageBuckets:   buckets,
+		pathPrefixes: parsePathPrefixes(*snapshotPathPrefixFlag),
+		snapshotsAgeDays: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "age_days"),
+			"Histogram of snapshot ages in days.",
+			[]string{"path_prefix"}, ConstLabels,
 		),
-		snapshots30DayCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "snapshots", "30_day_count"),
-			"Number of snapshots older than 30 days",
-			nil, ConstLabels,
-		),
-		snapshots60DayCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "snapshots", "60_day_count"),
-			"Number of snapshots older than 60 days.",
-			nil, ConstLabels,
-		),
-		snapshots90DayCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "snapshots", "90_day_count"),
-			"Number of snapshots older than 90 days.",
-			nil, ConstLabels,
+		snapshotsAgeSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "age_days_size_bytes"),
+			"Size in bytes of snapshots whose age in days is less than or equal to the bucket boundary.",
+			[]string{"path_prefix", "le"}, ConstLabels,
 		),
 		snapshotsActiveCount: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "snapshots", "active_count"),
@@ -130,48 +141,125 @@ func (c *snapshotsCollector) updateSummary(ch chan<- prometheus.Metric) error {
 	return nil
 }
 
+//ageHistogram accumulates snapshot ages for a single path prefix.
+type ageHistogram struct {
+	Count   uint64
+	Sum     float64
+	Buckets map[float64]uint64
+	Sizes   map[float64]float64
+}
+
+func newAgeHistogram() *ageHistogram {
+	return &ageHistogram{
+		Buckets: make(map[float64]uint64),
+		Sizes:   make(map[float64]float64),
+	}
+}
+
 func (c *snapshotsCollector) updateDayCounts(ch chan<- prometheus.Metric) error {
-	type TimeThreshold struct {
-		Name     string
-		Days     int64
-		Counter  int
-		PromDesc *prometheus.Desc
+	histograms := make(map[string]*ageHistogram)
+	it := isiclient.NewSnapshotsIterator(IsiCluster.Client)
+	var page isiclient.IsiSnapshots
+	for it.Next(&page) {
+		c.observeSnapshots(histograms, page)
+	}
+	if err := it.Err(); err != nil {
+		return err
 	}
 
-	//You should increase the size of the array if you are adding new thresholds.
-	//Make sure to add a new prometheus descriptor to the snapshotsCollector stuct.
-	//Make sure to keep thresholds in increasing order of days.
-	var thresholds = []TimeThreshold{
-		{"7d", 7, 0, c.snapshots7DayCount},
-		{"15d", 15, 0, c.snapshots15DayCount},
-		{"30d", 30, 0, c.snapshots30DayCount},
-		{"60d", 60, 0, c.snapshots60DayCount},
-		{"90d", 90, 0, c.snapshots90DayCount},
+	//Always report the unpartitioned series, even if there are no snapshots.
+	if len(c.pathPrefixes) == 0 && len(histograms) == 0 {
+		histograms["all"] = newAgeHistogram()
 	}
 
-	resp, err := isiclient.GetSnapshots(IsiCluster.Client)
-	if err != nil {
-		return err
+	for prefix, h := range histograms {
+		ch <- prometheus.MustNewConstHistogram(c.snapshotsAgeDays, h.Count, h.Sum, h.Buckets, prefix)
+		for _, bound := range c.ageBuckets {
+			le := strconv.FormatFloat(bound, 'f', -1, 64)
+			ch <- prometheus.MustNewConstMetric(c.snapshotsAgeSize, prometheus.GaugeValue, h.Sizes[bound], prefix, le)
+		}
 	}
+	return nil
+}
+
+//observeSnapshots adds every snapshot in a page to the histogram of its path prefix.
+func (c *snapshotsCollector) observeSnapshots(histograms map[string]*ageHistogram, page isiclient.IsiSnapshots) {
+	for _, snapshot := range page.Snapshots {
+		prefix := c.matchPathPrefix(snapshot.Path)
+		h, ok := histograms[prefix]
+		if !ok {
+			h = newAgeHistogram()
+			histograms[prefix] = h
+		}
 
-	for _, snapshot := range resp.Snapshots {
 		elapsed := time.Since(time.Unix(snapshot.Created, 0))
-		days := RoundTime(elapsed.Seconds() / 86400)
-		for idx := range thresholds {
-			if days >= thresholds[idx].Days {
-				thresholds[idx].Counter++
-			} else {
-				//We assume consistent order in the thresold array.
-				//Since the thresolds only get larger, if we fail one then move on to the next snapshot.
-				break
+		days := elapsed.Hours() / 24
+		h.Count++
+		h.Sum += days
+		for _, bound := range c.ageBuckets {
+			//Buckets are cumulative so a snapshot counts towards every boundary it is younger than.
+			if days <= bound {
+				h.Buckets[bound]++
+				h.Sizes[bound] += snapshot.Size
 			}
 		}
 	}
+}
 
-	for idx := range thresholds {
-		ch <- prometheus.MustNewConstMetric(thresholds[idx].PromDesc, prometheus.GaugeValue, float64(thresholds[idx].Counter))
+//matchPathPrefix returns the longest configured prefix the path falls under.
+func (c *snapshotsCollector) matchPathPrefix(path string) string {
+	if len(c.pathPrefixes) == 0 {
+		return "all"
 	}
-	return nil
+	match := "other"
+	for _, prefix := range c.pathPrefixes {
+		if path != prefix && !strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			continue
+		}
+		if match == "other" || len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	return match
+}
+
+//parseAgeBuckets converts a comma separated list of days into sorted bucket boundaries. Repeated boundaries are only kept once.
+func parseAgeBuckets(input string) ([]float64, error) {
+	var buckets []float64
+	for _, field := range strings.Split(input, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		days, err := strconv.ParseFloat(field, 64)
+		if err != nil || days < 0 || math.IsNaN(days) || math.IsInf(days, 0) {
+			return nil, fmt.Errorf("Invalid snapshot age bucket: %s", field)
+		}
+		buckets = append(buckets, days)
+	}
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("No snapshot age buckets specified")
+	}
+	sort.Float64s(buckets)
+	unique := buckets[:1]
+	for _, bound := range buckets[1:] {
+		if bound != unique[len(unique)-1] {
+			unique = append(unique, bound)
+		}
+	}
+	return unique, nil
+}
+
+//parsePathPrefixes converts a comma separated list of paths into a slice.
+func parsePathPrefixes(input string) []string {
+	var prefixes []string
+	for _, field := range strings.Split(input, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			prefixes = append(prefixes, field)
+		}
+	}
+	return prefixes
 }
 
 //RoundTime - Well gotta deal with those floating point numbers somehow
@@ -11,7 +11,8 @@ written permission of Adobe.
 package collector
 
 import (
-	"strconv"
+	"fmt"
+	"math"
 
 	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
@@ -29,8 +30,19 @@ type storagePoolsCollector struct {
 	storagePoolTotalBytes          *prometheus.Desc
 	storagePoolTotalSSDBytes       *prometheus.Desc
 	storagePoolVirtalHotSpareBytes *prometheus.Desc
+	storagePoolAvailHDDBytes       *prometheus.Desc
+	storagePoolFreeHDDBytes        *prometheus.Desc
+	storagePoolTotalHDDBytes       *prometheus.Desc
+	storagePoolUsedPercent         *prometheus.Desc
+	storagePoolInfo                *prometheus.Desc
+	storagePoolHealth              *prometheus.Desc
+	storagePoolHealthFlag          *prometheus.Desc
+	storagePoolL3                  *prometheus.Desc
 }
 
+//storagePoolLabelNames are the labels every per pool metric has.
+var storagePoolLabelNames = []string{"name"}
+
 func init() {
 	registerCollector("storage_pools", defaultEnabled, NewStoragePoolsCollector)
 }
@@ -46,47 +58,87 @@ func NewStoragePoolsCollector() (Collector, error) {
 		storagePoolManual: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "manual"),
 			"0 of storage pool is not manually managed, 1 is it is.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolAvailBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_avail"),
 			"Number of bytes available on the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolAvailSSDBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_avail_ssd"),
 			"Number of bytes available on ssd for the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolBalaced: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "balanced"),
 			"0 if the storage pool is balanced, 1 if it is not.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolFreeBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_free"),
 			"Number of bytes available on the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolFreeSSDBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_free_ssd"),
 			"Number of bytes free on ssd for the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolTotalBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_total"),
 			"Total number of bytes on the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolTotalSSDBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_total_ssd"),
 			"Total number of bytes on ssd for the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
 		),
 		storagePoolVirtalHotSpareBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "storage_pool", "bytes_virtual_hot_spare"),
 			"Number of bytes in vhs for the storage pool.",
-			[]string{"name"}, ConstLabels,
+			storagePoolLabelNames, ConstLabels,
+		),
+		storagePoolAvailHDDBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "bytes_avail_hdd"),
+			"Number of bytes available on hdd for the storage pool.",
+			storagePoolLabelNames, ConstLabels,
+		),
+		storagePoolFreeHDDBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "bytes_free_hdd"),
+			"Number of bytes free on hdd for the storage pool.",
+			storagePoolLabelNames, ConstLabels,
+		),
+		storagePoolTotalHDDBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "bytes_total_hdd"),
+			"Total number of bytes on hdd for the storage pool.",
+			storagePoolLabelNames, ConstLabels,
+		),
+		storagePoolUsedPercent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "used_percent"),
+			"Percentage of the storage pool that is not available for writes, by media (all, ssd, hdd).",
+			[]string{"name", "media"}, ConstLabels,
+		),
+		storagePoolInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "info"),
+			"Contains information about the storage pool in labels. Always returns a 1. Type is one of nodepool, tier or diskpool, parent is the tier or node pool containing the pool.",
+			[]string{"name", "type", "id", "parent", "protection_policy", "l3_status"}, ConstLabels,
+		),
+		storagePoolHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "health_flags"),
+			"Number of health flags raised on the storage pool. 0 if the pool is healthy.",
+			storagePoolLabelNames, ConstLabels,
+		),
+		storagePoolHealthFlag: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "health_flag"),
+			"Always 1 for every health flag raised on the storage pool.",
+			[]string{"name", "flag"}, ConstLabels,
+		),
+		storagePoolL3: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "storage_pool", "l3_enabled"),
+			"1 if L3 cache is enabled on the node pool, 0 if not.",
+			storagePoolLabelNames, ConstLabels,
 		),
 	}, nil
 }
@@ -98,75 +150,108 @@ func (c *storagePoolsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 	ch <- prometheus.MustNewConstMetric(c.storagePoolTotal, prometheus.GaugeValue, resp.Total)
 
+	//Pools only list their children so work out the parent of every pool first.
+	parents := make(map[string]string)
+	for _, pool := range resp.Storagepools {
+		for _, child := range pool.Children {
+			parents[child] = pool.Name
+		}
+	}
+
 	for _, pool := range resp.Storagepools {
 		var (
 			manual   float64
 			balanced float64
 		)
+		lv := []string{pool.Name}
+
 		if pool.Manual {
 			manual = 1
 		} else {
 			manual = 0
 		}
-		ch <- prometheus.MustNewConstMetric(c.storagePoolManual, prometheus.GaugeValue, manual, pool.Name)
+		ch <- prometheus.MustNewConstMetric(c.storagePoolManual, prometheus.GaugeValue, manual, lv...)
 
 		if pool.Usage.Balanced {
 			balanced = 0
 		} else {
 			balanced = 1
 		}
-		ch <- prometheus.MustNewConstMetric(c.storagePoolBalaced, prometheus.GaugeValue, balanced, pool.Name)
+		ch <- prometheus.MustNewConstMetric(c.storagePoolBalaced, prometheus.GaugeValue, balanced, lv...)
 
-		bAvail, err := strconv.Atoi(pool.Usage.AvailBytes)
-		if err != nil {
-			log.Warn("Unable to convert availbytes to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolAvailBytes, prometheus.GaugeValue, float64(bAvail), pool.Name)
-		}
+		ch <- prometheus.MustNewConstMetric(c.storagePoolInfo, prometheus.GaugeValue, 1, pool.Name, pool.Type, fmt.Sprintf("%v", pool.ID),
+			parents[pool.Name], pool.ProtectionPolicy, pool.L3Status)
 
-		bAvailSSD, err := strconv.Atoi(pool.Usage.AvailSsdBytes)
-		if err != nil {
-			log.Warn("Unable to convert availbytesssd to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolAvailSSDBytes, prometheus.GaugeValue, float64(bAvailSSD), pool.Name)
+		ch <- prometheus.MustNewConstMetric(c.storagePoolHealth, prometheus.GaugeValue, float64(len(pool.HealthFlags)), lv...)
+		for _, flag := range pool.HealthFlags {
+			ch <- prometheus.MustNewConstMetric(c.storagePoolHealthFlag, prometheus.GaugeValue, 1, pool.Name, fmt.Sprintf("%v", flag))
 		}
 
-		bFree, err := strconv.Atoi(pool.Usage.FreeBytes)
-		if err != nil {
-			log.Warn("Unable to convert freebytes to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolFreeBytes, prometheus.GaugeValue, float64(bFree), pool.Name)
+		//Only node pools have an L3 cache.
+		if pool.Type == "nodepool" {
+			var l3 float64
+			if pool.L3 {
+				l3 = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.storagePoolL3, prometheus.GaugeValue, l3, lv...)
 		}
 
-		bFreeSSD, err := strconv.Atoi(pool.Usage.FreeSsdBytes)
-		if err != nil {
-			log.Warn("Unable to convert freebytesssd to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolFreeSSDBytes, prometheus.GaugeValue, float64(bFreeSSD), pool.Name)
-		}
+		c.updateUsage(ch, pool, lv)
+	}
 
-		bTotal, err := strconv.Atoi(pool.Usage.TotalBytes)
-		if err != nil {
-			log.Warn("Unable to convert totalbytes to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolTotalBytes, prometheus.GaugeValue, float64(bTotal), pool.Name)
-		}
+	return nil
+}
 
-		bTotalSsd, err := strconv.Atoi(pool.Usage.TotalSsdBytes)
-		if err != nil {
-			log.Warn("Unable to convert totalssdbytes to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolTotalSSDBytes, prometheus.GaugeValue, float64(bTotalSsd), pool.Name)
+func (c *storagePoolsCollector) updateUsage(ch chan<- prometheus.Metric, pool isiclient.IsiStoragePool, lv []string) {
+	usage := pool.Usage
+	emit := func(desc *prometheus.Desc, name string, value isiclient.IsiNumber) {
+		if !value.Valid() {
+			log.Warnf("Unable to convert %s of storage pool %s to a number.", name, pool.Name)
+			return
 		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), lv...)
+	}
+	emit(c.storagePoolAvailBytes, "avail_bytes", usage.AvailBytes)
+	emit(c.storagePoolAvailSSDBytes, "avail_ssd_bytes", usage.AvailSsdBytes)
+	emit(c.storagePoolFreeBytes, "free_bytes", usage.FreeBytes)
+	emit(c.storagePoolFreeSSDBytes, "free_ssd_bytes", usage.FreeSsdBytes)
+	emit(c.storagePoolTotalBytes, "total_bytes", usage.TotalBytes)
+	emit(c.storagePoolTotalSSDBytes, "total_ssd_bytes", usage.TotalSsdBytes)
+	emit(c.storagePoolVirtalHotSpareBytes, "virtual_hot_spare_bytes", usage.VirtualHotSpareBytes)
 
-		bVHS, err := strconv.Atoi(pool.Usage.VirtualHotSpareBytes)
-		if err != nil {
-			log.Warn("Unable to convert virtualhotsparebytes to int.")
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.storagePoolVirtalHotSpareBytes, prometheus.GaugeValue, float64(bVHS), pool.Name)
-		}
+	avail, availSSD := float64(usage.AvailBytes), float64(usage.AvailSsdBytes)
+	free, freeSSD := float64(usage.FreeBytes), float64(usage.FreeSsdBytes)
+	total, totalSSD := float64(usage.TotalBytes), float64(usage.TotalSsdBytes)
 
+	//Whatever is not on ssd is on hdd. Values derived from a field that did not parse are NaN and left out.
+	derived := []struct {
+		desc  *prometheus.Desc
+		value float64
+	}{
+		{c.storagePoolAvailHDDBytes, avail - availSSD},
+		{c.storagePoolFreeHDDBytes, free - freeSSD},
+		{c.storagePoolTotalHDDBytes, total - totalSSD},
+	}
+	for _, d := range derived {
+		if !math.IsNaN(d.value) {
+			ch <- prometheus.MustNewConstMetric(d.desc, prometheus.GaugeValue, d.value, lv...)
+		}
 	}
 
-	return err
+	media := []struct {
+		name  string
+		avail float64
+		total float64
+	}{
+		{"all", avail, total},
+		{"ssd", availSSD, totalSSD},
+		{"hdd", avail - availSSD, total - totalSSD},
+	}
+	for _, m := range media {
+		//A pool without any of the media has no fullness.
+		if math.IsNaN(m.avail) || math.IsNaN(m.total) || m.total <= 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.storagePoolUsedPercent, prometheus.GaugeValue, (m.total-m.avail)/m.total*100, append(lv, m.name)...)
+	}
 }
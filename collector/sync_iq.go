@@ -71,11 +71,22 @@ func NewSyncIQCollector() (Collector, error) {
 }
 
 func (c *syncIQPoliciesCollector) Update(ch chan<- prometheus.Metric) error {
-	resp, err := isiclient.GetSyncPolicies(IsiCluster.Client)
+	var total int
+	it := isiclient.NewSyncPoliciesIterator(IsiCluster.Client)
+	var page isiclient.IsiSyncPolicies
+	for it.Next(&page) {
+		total += len(page.Policies)
+		c.updatePolicies(ch, page)
+	}
+	err := it.Err()
 	if err != nil {
 		log.Warnf("Error attempting to view sync policies.")
 	}
-	ch <- prometheus.MustNewConstMetric(c.syncPolicyTotalCount, prometheus.GaugeValue, float64(len(resp.Policies)))
+	ch <- prometheus.MustNewConstMetric(c.syncPolicyTotalCount, prometheus.GaugeValue, float64(total))
+	return err
+}
+
+func (c *syncIQPoliciesCollector) updatePolicies(ch chan<- prometheus.Metric, resp isiclient.IsiSyncPolicies) {
 	for _, policy := range resp.Policies {
 		var enabled float64
 		if policy.Enabled {
@@ -97,5 +108,4 @@ func (c *syncIQPoliciesCollector) Update(ch chan<- prometheus.Metric) error {
 		ch <- prometheus.MustNewConstMetric(c.syncPolicyState, prometheus.GaugeValue, state, policy.Name)
 		ch <- prometheus.MustNewConstMetric(c.syncPolicyWorkersPerNode, prometheus.GaugeValue, policy.WorkersPerNode, policy.Name)
 	}
-	return err
 }
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type workloadCollector struct {
+	workloadOps          *prometheus.Desc
+	workloadReads        *prometheus.Desc
+	workloadWrites       *prometheus.Desc
+	workloadBytesIn      *prometheus.Desc
+	workloadBytesOut     *prometheus.Desc
+	workloadLatencyRead  *prometheus.Desc
+	workloadLatencyWrite *prometheus.Desc
+	workloadLatencyOther *prometheus.Desc
+	workloadCPU          *prometheus.Desc
+	workloadL2           *prometheus.Desc
+	workloadL3           *prometheus.Desc
+	datasetWorkloads     *prometheus.Desc
+	datasetPinned        *prometheus.Desc
+}
+
+var (
+	datasetFlag *[]string
+
+	//workloadLabelNames are the labels every per workload metric has.
+	workloadLabelNames = []string{"dataset", "workload_id", "workload_type", "node", "user", "group", "zone", "path", "export_id", "share", "protocol", "remote_address"}
+)
+
+func init() {
+	registerCollector("workload", defaultDisabled, NewWorkloadCollector)
+
+	//Performance dataset flag.
+	datasetFlagName := "collector.workload.dataset"
+	datasetFlagHelp := "Name of a performance dataset to collect workload statistics for (default: all datasets). May be repeated."
+	datasetFlag = kingpin.Flag(datasetFlagName, datasetFlagHelp).Strings()
+}
+
+//newWorkloadDesc returns a desc for a per workload statistic.
+func newWorkloadDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "workload", name),
+		help,
+		workloadLabelNames, ConstLabels,
+	)
+}
+
+//NewWorkloadCollector returns a new Collector exposing partitioned performance workload statistics (OneFS 8.2+).
+func NewWorkloadCollector() (Collector, error) {
+	return &workloadCollector{
+		workloadOps:          newWorkloadDesc("ops", "Workload operations per second."),
+		workloadReads:        newWorkloadDesc("reads", "Workload reads per second."),
+		workloadWrites:       newWorkloadDesc("writes", "Workload writes per second."),
+		workloadBytesIn:      newWorkloadDesc("bytes_in", "Workload bytes in per second."),
+		workloadBytesOut:     newWorkloadDesc("bytes_out", "Workload bytes out per second."),
+		workloadLatencyRead:  newWorkloadDesc("latency_read", "Workload average read latency in microseconds."),
+		workloadLatencyWrite: newWorkloadDesc("latency_write", "Workload average write latency in microseconds."),
+		workloadLatencyOther: newWorkloadDesc("latency_other", "Workload average latency of other operations in microseconds."),
+		workloadCPU:          newWorkloadDesc("cpu", "Workload cpu time in microseconds per second."),
+		workloadL2:           newWorkloadDesc("l2", "Workload L2 cache hits per second."),
+		workloadL3:           newWorkloadDesc("l3", "Workload L3 cache hits per second."),
+		datasetWorkloads: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "workload", "dataset_workloads"),
+			"Number of workloads reported for the performance dataset by workload type (pinned, top workloads, system, etc.).",
+			[]string{"dataset", "workload_type"}, ConstLabels,
+		),
+		datasetPinned: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "workload", "dataset_pinned_workloads"),
+			"Number of workloads pinned to the performance dataset.",
+			[]string{"dataset"}, ConstLabels,
+		),
+	}, nil
+}
+
+func (c *workloadCollector) Update(ch chan<- prometheus.Metric) error {
+	var datasets []isiclient.IsiPerformanceDataset
+	it := isiclient.NewPerformanceDatasetsIterator(IsiCluster.Client)
+	var page isiclient.IsiPerformanceDatasets
+	for it.Next(&page) {
+		datasets = append(datasets, page.Datasets...)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range *datasetFlag {
+		wanted[name] = true
+	}
+
+	var errCount int64
+	for _, dataset := range datasets {
+		if len(wanted) > 0 && !wanted[dataset.Name] {
+			continue
+		}
+		err := c.updateDataset(ch, dataset)
+		if err != nil {
+			log.Warnf("Unable to collect workloads of dataset %s: %s", dataset.Name, err)
+			errCount++
+		}
+	}
+	if errCount != 0 {
+		err := fmt.Errorf("There where %v errors", errCount)
+		return err
+	}
+	return nil
+}
+
+func (c *workloadCollector) updateDataset(ch chan<- prometheus.Metric, dataset isiclient.IsiPerformanceDataset) error {
+	id := fmt.Sprintf("%v", dataset.ID)
+
+	pinned, err := isiclient.GetPerformanceDatasetWorkloads(IsiCluster.Client, id)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.datasetPinned, prometheus.GaugeValue, float64(len(pinned.Workloads)), dataset.Name)
+
+	resp, err := isiclient.GetWorkloadSummary(IsiCluster.Client, id)
+	if err != nil {
+		return err
+	}
+	types := make(map[string]float64)
+	for _, w := range resp.Workload {
+		if w.Error != "" {
+			log.Debugf("Workload %v of dataset %s on node %v: %s", w.WorkloadID, dataset.Name, w.Node, w.Error)
+			continue
+		}
+		types[w.WorkloadType]++
+
+		var exportID string
+		if w.ExportID != 0 {
+			exportID = fmt.Sprintf("%v", w.ExportID)
+		}
+		lv := []string{dataset.Name, fmt.Sprintf("%v", w.WorkloadID), w.WorkloadType, fmt.Sprintf("%v", w.Node), w.Username, w.Groupname, w.ZoneName,
+			w.Path, exportID, w.ShareName, w.Protocol, w.RemoteAddress}
+		ch <- prometheus.MustNewConstMetric(c.workloadOps, prometheus.GaugeValue, w.Ops, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadReads, prometheus.GaugeValue, w.Reads, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadWrites, prometheus.GaugeValue, w.Writes, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadBytesIn, prometheus.GaugeValue, w.BytesIn, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadBytesOut, prometheus.GaugeValue, w.BytesOut, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadLatencyRead, prometheus.GaugeValue, w.LatencyRead, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadLatencyWrite, prometheus.GaugeValue, w.LatencyWrite, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadLatencyOther, prometheus.GaugeValue, w.LatencyOther, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadCPU, prometheus.GaugeValue, w.CPU, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadL2, prometheus.GaugeValue, w.L2, lv...)
+		ch <- prometheus.MustNewConstMetric(c.workloadL3, prometheus.GaugeValue, w.L3, lv...)
+	}
+	for workloadType, count := range types {
+		ch <- prometheus.MustNewConstMetric(c.datasetWorkloads, prometheus.GaugeValue, count, dataset.Name, workloadType)
+	}
+	return nil
+}
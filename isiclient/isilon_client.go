@@ -141,70 +141,68 @@ func GetOneFsVersion(c *goisilon.Client) (string, error) {
 	return resp.OnefsVersion.Release, nil
 }
 
-//GetQuotas returns all quotas from the api
-func GetQuotas(c *goisilon.Client, exceeded bool) (IsiQuotas, error) {
-	var (
-		path   = "/platform/1/quota/quotas"
-		resp   IsiQuotas
-		params api.OrderedValues
-		token  = ""
-	)
-	params = api.NewOrderedValues([][]string{
-		{"resolve_names", "true"},
-		{"resume", token},
-	})
+//GetClusterConfig will grab the cluster config from the api, including node membership and quorum
+func GetClusterConfig(c *goisilon.Client) (IsiConfig, error) {
+	const path = "/platform/3/cluster/config"
+	var resp IsiConfig
 
-	if exceeded {
-		params.StringSet("exceeded", "true")
-	}
-
-	fmt.Printf("%v", params)
-
-	err := c.API.Get(context.Background(), path, "", params, nil, &resp)
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
 	if err != nil {
-		log.Warnf("Unable to get quotas from api: %s", err)
+		log.Warnf("Unable to get cluster config from api: %s", err)
+		return resp, err
 	}
 	return resp, nil
 }
 
-func GetAllQuotas(c *goisilon.Client, exceeded bool) (IsiQuotas, error) {
-	var (
-		path   = "/platform/1/quota/quotas"
-		resp   IsiQuotas
-		params api.OrderedValues
-	)
-	params = api.NewOrderedValues([][]string{
-		{"resolve_names", "true"},
+//NewQuotasIterator returns a page iterator over quotas of the given type ("all" for every type).
+//Persona names are only resolved by the api if resolveNames is set.
+//If reportID is set the quotas are read from that quota report instead of the live quota data.
+func NewQuotasIterator(c *goisilon.Client, exceeded bool, qtype string, resolveNames bool, reportID string) *PageIterator {
+	const path = "/platform/1/quota/quotas"
+	params := api.NewOrderedValues([][]string{
+		{"resolve_names", fmt.Sprintf("%v", resolveNames)},
 	})
+	if reportID != "" {
+		params.StringSet("report_id", reportID)
+	}
+	if qtype != "all" {
+		params.StringSet("type", qtype)
+	}
 	if exceeded {
 		params.StringSet("exceeded", "true")
 	}
-
-	err := c.API.Get(context.Background(), path, "", params, nil, &resp)
-	if err != nil {
-		log.Warnf("Unable to get quotas from api: %s", err)
-	}
-	return resp, nil
+	return NewPageIterator(c, path, params)
 }
 
-func GetQuotasOfType(c *goisilon.Client, exceeded bool, qtype string) (IsiQuotas, error) {
-	var (
-		path   = "/platform/1/quota/quotas"
-		resp   IsiQuotas
-		params api.OrderedValues
-	)
-	params = api.NewOrderedValues([][]string{
-		{"resolve_names", "true"},
-		{"type", qtype},
+//NewQuotaReportsIterator returns a page iterator over the quota reports of the given generation (scheduled, manual, live).
+func NewQuotaReportsIterator(c *goisilon.Client, generated string) *PageIterator {
+	const path = "/platform/1/quota/reports"
+	params := api.NewOrderedValues([][]string{
+		{"generated", generated},
 	})
+	return NewPageIterator(c, path, params)
+}
 
-	if exceeded {
-		params.StringSet("exceeded", "true")
+//GetQuotaNotifications returns the notification rules of a quota. Only quotas with custom notifications have rules of their own.
+func GetQuotaNotifications(c *goisilon.Client, id string) (IsiQuotaNotifications, error) {
+	path := fmt.Sprintf("/platform/1/quota/quotas/%s/notifications", id)
+	var resp IsiQuotaNotifications
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to get notification rules of quota %s: %s", id, err)
+		return resp, err
 	}
+	return resp, nil
+}
 
-	err := c.API.Get(context.Background(), path, "", params, nil, &resp)
+//GetQuotaDefaultNotifications returns the notification rules used by quotas with default notifications.
+func GetQuotaDefaultNotifications(c *goisilon.Client) (IsiQuotaNotifications, error) {
+	const path = "/platform/1/quota/settings/notifications"
+	var resp IsiQuotaNotifications
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
 	if err != nil {
-		log.Warnf("Unable to get quotas from api: %s", err)
+		log.Warnf("Unable to get default quota notification rules: %s", err)
+		return resp, err
 	}
 	return resp, nil
 }
@@ -218,25 +216,18 @@ func GetQuotaSummary(c *goisilon.Client) (IsiQuotaSummary, error) {
 	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
 	if err != nil {
 		log.Warnf("Unable to get summary about quotas from api: %s", err)
+		return resp.Summary, err
 	}
 	return resp.Summary, nil
 }
 
-func GetQuotasWithResume(c *goisilon.Client, rtoken string) (IsiQuotas, error) {
-	var (
-		path   = "/platform/1/quota/quotas"
-		resp   IsiQuotas
-		params api.OrderedValues
-	)
-	params = api.NewOrderedValues([][]string{
-		{"resume", rtoken},
+//NewStatsKeysIterator returns a page iterator over the keys the stats engine can be queried for.
+func NewStatsKeysIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/1/statistics/keys"
+	params := api.NewOrderedValues([][]string{
+		{"queryable", "true"},
 	})
-
-	err := c.API.Get(context.Background(), path, "", params, nil, &resp)
-	if err != nil {
-		log.Warnf("Unable to get quotas from api with resume token %s : %s ", rtoken, err)
-	}
-	return resp, nil
+	return NewPageIterator(c, path, params)
 }
 
 //GetProtoStat for protocol level information
@@ -263,17 +254,10 @@ func GetProtoStat(c *goisilon.Client, key string) (IsiProtoStat, error) {
 	return resp, nil
 }
 
-//GetSyncPolicies retrieve all sync iq policies
-func GetSyncPolicies(c *goisilon.Client) (IsiSyncPolicies, error) {
+//NewSyncPoliciesIterator returns a page iterator over all sync iq policies.
+func NewSyncPoliciesIterator(c *goisilon.Client) *PageIterator {
 	const path = "/platform/3/sync/policies"
-	var resp IsiSyncPolicies
-
-	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
-	if err != nil {
-		log.Warnf("Unable to retrieve sync")
-		return resp, err
-	}
-	return resp, nil
+	return NewPageIterator(c, path, nil)
 }
 
 //GetSnapshotsSummary retrieves summary statistics for snapshots
@@ -289,17 +273,10 @@ func GetSnapshotsSummary(c *goisilon.Client) (IsiSnapshotsSummary, error) {
 	return resp, nil
 }
 
-//GetSnapshots returns a struct with all snapshots.
-func GetSnapshots(c *goisilon.Client) (IsiSnapshots, error) {
+//NewSnapshotsIterator returns a page iterator over all snapshots.
+func NewSnapshotsIterator(c *goisilon.Client) *PageIterator {
 	const path = "/platform/1/snapshot/snapshots"
-	var resp IsiSnapshots
-
-	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
-	if err != nil {
-		log.Warnf("Unable to retrieve snapshots. Err: %s", err)
-		return resp, err
-	}
-	return resp, nil
+	return NewPageIterator(c, path, nil)
 }
 
 func GetNodesPartitions(c *goisilon.Client) (IsiNodesPartitions, error) {
@@ -338,6 +315,19 @@ func GetNodesHardware(c *goisilon.Client) (IsiNodesHardware, error) {
 	return resp, nil
 }
 
+//GetNodesSensors returns the hardware sensor readings of every node.
+func GetNodesSensors(c *goisilon.Client) (IsiNodesSensors, error) {
+	const path = "/platform/3/cluster/nodes/all/sensors"
+	var resp IsiNodesSensors
+
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get nodes sensors.")
+		return resp, err
+	}
+	return resp, nil
+}
+
 func GetNodesState(c *goisilon.Client) (IsiNodesState, error) {
 	const path = "/platform/3/cluster/nodes/all/state"
 	var resp IsiNodesState
@@ -374,6 +364,30 @@ func GetExportSummary(c *goisilon.Client) (IsiExportSummary, error) {
 	return resp, nil
 }
 
+//NewNfsExportsIterator returns a page iterator over the nfs exports of an access zone.
+func NewNfsExportsIterator(c *goisilon.Client, zone string) *PageIterator {
+	const path = "/platform/2/protocols/nfs/exports"
+	params := api.NewOrderedValues([][]string{
+		{"zone", zone},
+	})
+	return NewPageIterator(c, path, params)
+}
+
+//GetNfsExportsCheck validates the nfs exports of an access zone and returns the problems found.
+func GetNfsExportsCheck(c *goisilon.Client, zone string) (IsiNfsExportsCheck, error) {
+	const path = "/platform/2/protocols/nfs/check"
+	var resp IsiNfsExportsCheck
+	params := api.NewOrderedValues([][]string{
+		{"zone", zone},
+	})
+	err := c.API.Get(context.Background(), path, "", params, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to check nfs exports of zone %s.", zone)
+		return resp, err
+	}
+	return resp, nil
+}
+
 func GetSharesSummary(c *goisilon.Client) (IsiSharesSummary, error) {
 	const path = "/platform/3/protocols/smb/shares-summary"
 	var resp IsiSharesSummary
@@ -398,6 +412,32 @@ func GetStoragePools(c *goisilon.Client) (IsiStoragePools, error) {
 	return resp, nil
 }
 
+//GetStoragePoolSettings returns the global SmartPools settings, including the virtual hot spare reservation.
+func GetStoragePoolSettings(c *goisilon.Client) (IsiStoragePoolSettings, error) {
+	const path = "/platform/1/storagepool/settings"
+	var resp IsiStoragePoolSettings
+
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get storagepool settings.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetSuggestedProtection returns the protection policy the cluster suggests for a node pool.
+func GetSuggestedProtection(c *goisilon.Client, nodePoolID string) (IsiSuggestedProtection, error) {
+	path := fmt.Sprintf("/platform/3/storagepool/suggested-protection/%s", nodePoolID)
+	var resp IsiSuggestedProtection
+
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to get suggested protection of node pool %s.", nodePoolID)
+		return resp, err
+	}
+	return resp, nil
+}
+
 func GetDriveInfo(c *goisilon.Client) (IsiNodesDrives, error) {
 	const path = "/platform/3/cluster/nodes/all/drives"
 	var resp IsiNodesDrives
@@ -408,3 +448,231 @@ func GetDriveInfo(c *goisilon.Client) (IsiNodesDrives, error) {
 	}
 	return resp, nil
 }
+
+//GetDriveFirmware returns the firmware of the drive in a bay of a node.
+func GetDriveFirmware(c *goisilon.Client, lnn string, bay string) (IsiDriveFirmware, error) {
+	path := fmt.Sprintf("/platform/3/cluster/nodes/%s/drives/%s/firmware", lnn, bay)
+	var resp IsiDriveFirmware
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to get firmware of drive %s on node %s.", bay, lnn)
+		return resp, err
+	}
+	return resp, nil
+}
+
+//NewSmbSharesIterator returns a page iterator over the smb shares of an access zone.
+func NewSmbSharesIterator(c *goisilon.Client, zone string) *PageIterator {
+	const path = "/platform/3/protocols/smb/shares"
+	params := api.NewOrderedValues([][]string{
+		{"zone", zone},
+	})
+	return NewPageIterator(c, path, params)
+}
+
+//NewSmbSessionsIterator returns a page iterator over the smb sessions open on the cluster.
+func NewSmbSessionsIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/1/protocols/smb/sessions"
+	return NewPageIterator(c, path, nil)
+}
+
+//NewSmbOpenfilesIterator returns a page iterator over the files opened over smb on the cluster.
+func NewSmbOpenfilesIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/1/protocols/smb/openfiles"
+	return NewPageIterator(c, path, nil)
+}
+
+//NewPerformanceDatasetsIterator returns a page iterator over the configured performance datasets.
+func NewPerformanceDatasetsIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/10/performance/datasets"
+	return NewPageIterator(c, path, nil)
+}
+
+//GetPerformanceDatasetWorkloads returns the pinned workloads of a performance dataset.
+func GetPerformanceDatasetWorkloads(c *goisilon.Client, dataset string) (IsiPerformanceWorkloads, error) {
+	path := fmt.Sprintf("/platform/10/performance/datasets/%s/workloads", dataset)
+	var resp IsiPerformanceWorkloads
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to get pinned workloads of dataset %s.", dataset)
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetWorkloadSummary returns the current statistics of the workloads of a performance dataset on every node.
+func GetWorkloadSummary(c *goisilon.Client, dataset string) (IsiWorkloadSummary, error) {
+	const path = "/platform/10/statistics/summary/workload"
+	var resp IsiWorkloadSummary
+	params := api.NewOrderedValues([][]string{
+		{"dataset", dataset},
+	})
+	err := c.API.Get(context.Background(), path, "", params, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to get workload statistics of dataset %s.", dataset)
+		return resp, err
+	}
+	return resp, nil
+}
+
+//NewNetworkPoolsIterator returns a page iterator over all network pools.
+func NewNetworkPoolsIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/3/network/pools"
+	return NewPageIterator(c, path, nil)
+}
+
+//NewNetworkSubnetsIterator returns a page iterator over all network subnets.
+func NewNetworkSubnetsIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/3/network/subnets"
+	return NewPageIterator(c, path, nil)
+}
+
+//NewNetworkInterfacesIterator returns a page iterator over the network interfaces of every node.
+func NewNetworkInterfacesIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/3/network/interfaces"
+	return NewPageIterator(c, path, nil)
+}
+
+//GetFilepoolPolicies returns the file pool policies in the order they are applied.
+func GetFilepoolPolicies(c *goisilon.Client) (IsiFilepoolPolicies, error) {
+	const path = "/platform/4/filepool/policies"
+	var resp IsiFilepoolPolicies
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get file pool policies.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetFilepoolDefaultPolicy returns the policy applied to files no other file pool policy matches.
+func GetFilepoolDefaultPolicy(c *goisilon.Client) (IsiFilepoolDefaultPolicy, error) {
+	const path = "/platform/4/filepool/default-policy"
+	var resp IsiFilepoolDefaultPolicy
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get the default file pool policy.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//NewJobReportsIterator returns a page iterator over the job engine reports of a job type since a unix timestamp.
+//Only the report of the last phase of each job is returned.
+func NewJobReportsIterator(c *goisilon.Client, jobType string, begin int64) *PageIterator {
+	const path = "/platform/3/job/reports"
+	params := api.NewOrderedValues([][]string{
+		{"job_type", jobType},
+		{"last_phase", "true"},
+		{"begin", fmt.Sprintf("%v", begin)},
+		{"verbose", "true"},
+	})
+	return NewPageIterator(c, path, params)
+}
+
+//GetDedupeSummary returns the SmartDedupe savings of the cluster.
+func GetDedupeSummary(c *goisilon.Client) (IsiDedupeSummary, error) {
+	const path = "/platform/1/dedupe/dedupe-summary"
+	var resp IsiDedupeSummary
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get dedupe summary.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//NewDedupeReportsIterator returns a page iterator over the reports of the dedupe jobs.
+func NewDedupeReportsIterator(c *goisilon.Client) *PageIterator {
+	const path = "/platform/1/dedupe/reports"
+	return NewPageIterator(c, path, nil)
+}
+
+//GetLicenses returns the status and expiration of every licensable feature.
+func GetLicenses(c *goisilon.Client) (IsiLicenses, error) {
+	const path = "/platform/5/license/licenses"
+	var resp IsiLicenses
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get licenses.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetAuthProvidersSummary returns the status of every authentication provider instance in every access zone.
+func GetAuthProvidersSummary(c *goisilon.Client) (IsiAuthProvidersSummary, error) {
+	const path = "/platform/3/auth/providers/summary"
+	var resp IsiAuthProvidersSummary
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get auth providers summary.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetAdsProviders returns the settings and status of the Active Directory providers.
+func GetAdsProviders(c *goisilon.Client) (IsiAdsProviders, error) {
+	const path = "/platform/3/auth/providers/ads"
+	var resp IsiAdsProviders
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get ads providers.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetAdsDomains returns the domains trusted by an Active Directory provider.
+func GetAdsDomains(c *goisilon.Client, id string) (IsiAdsDomains, error) {
+	path := fmt.Sprintf("/platform/3/auth/providers/ads/%s/domains", id)
+	var resp IsiAdsDomains
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warnf("Unable to get trusted domains of ads provider %s.", id)
+		return resp, err
+	}
+	return resp, nil
+}
+
+//GetZones returns all access zones on the cluster.
+func GetZones(c *goisilon.Client) (IsiZones, error) {
+	const path = "/platform/3/zones"
+	var resp IsiZones
+	err := c.API.Get(context.Background(), path, "", nil, nil, &resp)
+	if err != nil {
+		log.Warn("Unable to get access zones.")
+		return resp, err
+	}
+	return resp, nil
+}
+
+//ResolvePersona looks up the name and the uid, gid and sid of a user or group persona from the auth providers.
+func ResolvePersona(c *goisilon.Client, persona IsiPersona) (IsiResolvedPersona, error) {
+	if persona.Type == "group" {
+		const path = "/platform/1/auth/groups"
+		var resp IsiAuthGroups
+		err := c.API.Get(context.Background(), path, persona.ID, nil, nil, &resp)
+		if err != nil {
+			return IsiResolvedPersona{}, err
+		}
+		if len(resp.Groups) == 0 {
+			return IsiResolvedPersona{}, fmt.Errorf("No group found for persona %s", persona.ID)
+		}
+		group := resp.Groups[0]
+		return IsiResolvedPersona{Name: group.Name, GID: group.GID.GID(), SID: group.SID.SID()}, nil
+	}
+
+	const path = "/platform/1/auth/users"
+	var resp IsiAuthUsers
+	err := c.API.Get(context.Background(), path, persona.ID, nil, nil, &resp)
+	if err != nil {
+		return IsiResolvedPersona{}, err
+	}
+	if len(resp.Users) == 0 {
+		return IsiResolvedPersona{}, fmt.Errorf("No user found for persona %s", persona.ID)
+	}
+	user := resp.Users[0]
+	return IsiResolvedPersona{Name: user.Name, UID: user.UID.UID(), SID: user.SID.SID()}, nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2018 Adobe
+All Rights Reserved.
+
+NOTICE: Adobe permits you to use, modify, and distribute this file in
+accordance with the terms of the Adobe license agreement accompanying
+it. If you have received this file from a source other than Adobe,
+then your use, modification, or distribution of it requires the prior
+written permission of Adobe.
+*/
+package isiclient
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/hpanike/goisilon"
+	"github.com/hpanike/goisilon/api"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	//PageLimit is the number of items requested per page from list endpoints. 0 leaves it to the cluster default.
+	PageLimit = 1000
+	//PageRetries is the number of times a single failed page is retried before giving up.
+	PageRetries = 3
+	//PageRetryDelay is the base delay between retries of a failed page. It is multiplied by the attempt number.
+	PageRetryDelay = time.Second
+	//PageRetryMaxWait caps the total time spent waiting between the retries of a single page.
+	PageRetryMaxWait = 5 * time.Second
+)
+
+//Page is implemented by a pointer to every list response that carries a resume token.
+type Page interface {
+	ResumeToken() string
+}
+
+//PageIterator walks a list endpoint one page at a time following resume tokens.
+type PageIterator struct {
+	//Retries is the number of times a failed page is retried, it defaults to PageRetries.
+	Retries int
+
+	client *goisilon.Client
+	path   string
+	params api.OrderedValues
+	resume string
+	pages  int
+	done   bool
+	err    error
+}
+
+//NewPageIterator returns an iterator over the list endpoint at path. The params are only sent with the first page.
+func NewPageIterator(c *goisilon.Client, path string, params api.OrderedValues) *PageIterator {
+	if PageLimit > 0 {
+		if params == nil {
+			params = api.NewOrderedValues([][]string{})
+		}
+		params.StringSet("limit", strconv.Itoa(PageLimit))
+	}
+	return &PageIterator{
+		Retries: PageRetries,
+		client:  c,
+		path:    path,
+		params:  params,
+	}
+}
+
+//Next fetches the next page into page, which must be a pointer to a response struct.
+//It returns false once there are no more pages or a page failed after all retries, check Err to tell them apart.
+func (it *PageIterator) Next(page Page) bool {
+	if it.done {
+		return false
+	}
+
+	params := it.params
+	if it.pages > 0 {
+		//The api does not accept any other options alongside a resume token.
+		params = api.NewOrderedValues([][]string{
+			{"resume", it.resume},
+		})
+	}
+
+	var (
+		err    error
+		waited time.Duration
+	)
+	for attempt := 0; attempt <= it.Retries; attempt++ {
+		if attempt > 0 {
+			//Stop retrying once the wait would run past the budget, so a bad page cannot outlast the scrape.
+			delay := time.Duration(attempt) * PageRetryDelay
+			if waited+delay > PageRetryMaxWait {
+				break
+			}
+			waited += delay
+			log.Infof("Retrying page %v of %s, attempt %v/%v", it.pages+1, it.path, attempt, it.Retries)
+			time.Sleep(delay)
+		}
+		//Reset the page so fields missing from this response do not leak over from the previous one.
+		v := reflect.ValueOf(page).Elem()
+		v.Set(reflect.Zero(v.Type()))
+
+		err = it.client.API.Get(context.Background(), it.path, "", params, nil, page)
+		if err == nil {
+			break
+		}
+		log.Warnf("Unable to retrieve page %v of %s: %s", it.pages+1, it.path, err)
+	}
+	if err != nil {
+		it.err = err
+		it.done = true
+		return false
+	}
+
+	it.pages++
+	it.resume = page.ResumeToken()
+	if it.resume == "" {
+		it.done = true
+	}
+	return true
+}
+
+//Err returns the error that stopped the iteration, if any.
+func (it *PageIterator) Err() error {
+	return it.err
+}
+
+//Pages returns the number of pages successfully retrieved so far.
+func (it *PageIterator) Pages() int {
+	return it.pages
+}
@@ -10,6 +10,13 @@ written permission of Adobe.
 */
 package isiclient
 
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
 // IsiConfig is used to unmarshal the config api response
 type IsiConfig struct {
 	Description string `json:"description"`
@@ -82,11 +89,31 @@ type IsiSingleVal struct {
 	} `json:"stats"`
 }
 
+type IsiStatsKeys struct {
+	Keys []struct {
+		Description string `json:"description"`
+		Key         string `json:"key"`
+		Units       string `json:"units"`
+	} `json:"keys"`
+	Resume string  `json:"resume"`
+	Total  float64 `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (k *IsiStatsKeys) ResumeToken() string {
+	return k.Resume
+}
+
 type IsiQuotas struct {
 	Quotas []IsiQuota `json:"quotas"`
 	Resume string     `json:"resume"`
 }
 
+//ResumeToken implements the Page interface.
+func (q *IsiQuotas) ResumeToken() string {
+	return q.Resume
+}
+
 type IsiQuota struct {
 	Container                 bool              `json:"container"`
 	Enforced                  bool              `json:"enforced"`
@@ -95,7 +122,7 @@ type IsiQuota struct {
 	Linked                    bool              `json:"linked"`
 	Notifications             string            `json:"notifications"`
 	Path                      string            `json:"path"`
-	Persona                   interface{}       `json:"persona"`
+	Persona                   *IsiPersona       `json:"persona"`
 	Ready                     bool              `json:"ready"`
 	Thresholds                IsiQuotaThreshold `json:"thresholds"`
 	ThresholdsIncludeOverhead bool              `json:"thresholds_include_overhead"`
@@ -103,6 +130,75 @@ type IsiQuota struct {
 	Usage                     IsiQuotaUsage     `json:"usage"`
 }
 
+//IsiPersona identifies the user or group a quota applies to.
+type IsiPersona struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+//UID returns the numeric user id if the persona is identified by one.
+func (p IsiPersona) UID() string {
+	return p.idOfKind("UID")
+}
+
+//GID returns the numeric group id if the persona is identified by one.
+func (p IsiPersona) GID() string {
+	return p.idOfKind("GID")
+}
+
+//SID returns the windows security identifier if the persona is identified by one.
+func (p IsiPersona) SID() string {
+	return p.idOfKind("SID")
+}
+
+//idOfKind strips the kind prefix from ids of the form KIND:value.
+func (p IsiPersona) idOfKind(kind string) string {
+	prefix := kind + ":"
+	if len(p.ID) > len(prefix) && p.ID[:len(prefix)] == prefix {
+		return p.ID[len(prefix):]
+	}
+	return ""
+}
+
+//IsiResolvedPersona is a persona looked up from the auth providers. Ids the provider does not know are empty.
+type IsiResolvedPersona struct {
+	Name string
+	UID  string
+	GID  string
+	SID  string
+}
+
+//Merge fills the ids missing from r with the ids p is identified by.
+func (r IsiResolvedPersona) Merge(p IsiPersona) IsiResolvedPersona {
+	if r.UID == "" {
+		r.UID = p.UID()
+	}
+	if r.GID == "" {
+		r.GID = p.GID()
+	}
+	if r.SID == "" {
+		r.SID = p.SID()
+	}
+	return r
+}
+
+type IsiAuthUsers struct {
+	Users []struct {
+		Name string     `json:"name"`
+		SID  IsiPersona `json:"sid"`
+		UID  IsiPersona `json:"uid"`
+	} `json:"users"`
+}
+
+type IsiAuthGroups struct {
+	Groups []struct {
+		Name string     `json:"name"`
+		GID  IsiPersona `json:"gid"`
+		SID  IsiPersona `json:"sid"`
+	} `json:"groups"`
+}
+
 type IsiQuotaUsage struct {
 	Inodes   float64 `json:"inodes"`
 	Logical  float64 `json:"logical"`
@@ -122,6 +218,45 @@ type IsiQuotaThreshold struct {
 	SoftLastExceeded     interface{} `json:"soft_last_exceeded"`
 }
 
+type IsiQuotaNotifications struct {
+	Notifications []IsiQuotaNotification `json:"notifications"`
+}
+
+type IsiQuotaNotification struct {
+	ActionAlert        bool    `json:"action_alert"`
+	ActionEmailAddress string  `json:"action_email_address"`
+	ActionEmailOwner   bool    `json:"action_email_owner"`
+	Condition          string  `json:"condition"`
+	EmailTemplate      string  `json:"email_template"`
+	Holdoff            float64 `json:"holdoff"`
+	ID                 string  `json:"id"`
+	Schedule           string  `json:"schedule"`
+	Threshold          string  `json:"threshold"`
+}
+
+//Notifies returns true if the rule raises an alert or sends an email to anyone.
+func (n IsiQuotaNotification) Notifies() bool {
+	return n.ActionAlert || n.ActionEmailOwner || n.ActionEmailAddress != ""
+}
+
+type IsiQuotaReports struct {
+	Reports []IsiQuotaReport `json:"reports"`
+	Resume  string           `json:"resume"`
+	Total   float64          `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (r *IsiQuotaReports) ResumeToken() string {
+	return r.Resume
+}
+
+type IsiQuotaReport struct {
+	Generated string  `json:"generated"`
+	ID        string  `json:"id"`
+	Time      float64 `json:"time"`
+	Type      string  `json:"type"`
+}
+
 type IsiQuotaSummaryResp struct {
 	Summary IsiQuotaSummary `json:"summary"`
 }
@@ -164,6 +299,175 @@ type IsiProtoStatOp struct {
 	TimeMin   float64 `json:"time_min"`
 }
 
+//IsiClientStat is the protocol activity of a single client from the node.clientstats.proto.<proto> keys.
+type IsiClientStat struct {
+	ClassName     string     `json:"class_name"`
+	InAvg         float64    `json:"in_avg"`
+	InMax         float64    `json:"in_max"`
+	InMin         float64    `json:"in_min"`
+	InRate        float64    `json:"in_rate"`
+	LocalAddr     string     `json:"local_addr"`
+	LocalName     string     `json:"local_name"`
+	Node          float64    `json:"node"`
+	NumOperations float64    `json:"num_operations"`
+	OperationRate float64    `json:"operation_rate"`
+	OutAvg        float64    `json:"out_avg"`
+	OutMax        float64    `json:"out_max"`
+	OutMin        float64    `json:"out_min"`
+	OutRate       float64    `json:"out_rate"`
+	Protocol      string     `json:"protocol"`
+	RemoteAddr    string     `json:"remote_addr"`
+	RemoteName    string     `json:"remote_name"`
+	TimeAvg       float64    `json:"time_avg"`
+	TimeMax       float64    `json:"time_max"`
+	TimeMin       float64    `json:"time_min"`
+	User          IsiPersona `json:"user"`
+}
+
+type IsiPerformanceDatasets struct {
+	Datasets []IsiPerformanceDataset `json:"datasets"`
+	Resume   string                  `json:"resume"`
+	Total    float64                 `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (d *IsiPerformanceDatasets) ResumeToken() string {
+	return d.Resume
+}
+
+type IsiPerformanceDataset struct {
+	CreationTime float64  `json:"creation_time"`
+	Filters      []string `json:"filters"`
+	ID           float64  `json:"id"`
+	Metrics      []string `json:"metrics"`
+	Name         string   `json:"name"`
+	Statkey      string   `json:"statkey"`
+}
+
+type IsiPerformanceWorkloads struct {
+	Workloads []struct {
+		ID   float64 `json:"id"`
+		Name string  `json:"name"`
+	} `json:"workloads"`
+}
+
+type IsiWorkloadSummary struct {
+	Workload []IsiWorkloadStat `json:"workload"`
+}
+
+type IsiWorkloadStat struct {
+	BytesIn       float64 `json:"bytes_in"`
+	BytesOut      float64 `json:"bytes_out"`
+	CPU           float64 `json:"cpu"`
+	DatasetID     float64 `json:"dataset_id"`
+	Error         string  `json:"error"`
+	ExportID      float64 `json:"export_id"`
+	Groupname     string  `json:"groupname"`
+	L2            float64 `json:"l2"`
+	L3            float64 `json:"l3"`
+	LatencyOther  float64 `json:"latency_other"`
+	LatencyRead   float64 `json:"latency_read"`
+	LatencyWrite  float64 `json:"latency_write"`
+	LocalAddress  string  `json:"local_address"`
+	Node          float64 `json:"node"`
+	Ops           float64 `json:"ops"`
+	Path          string  `json:"path"`
+	Protocol      string  `json:"protocol"`
+	Reads         float64 `json:"reads"`
+	RemoteAddress string  `json:"remote_address"`
+	ShareName     string  `json:"share_name"`
+	Time          float64 `json:"time"`
+	Username      string  `json:"username"`
+	WorkloadID    float64 `json:"workload_id"`
+	WorkloadType  string  `json:"workload_type"`
+	Writes        float64 `json:"writes"`
+	ZoneName      string  `json:"zone_name"`
+}
+
+type IsiNetworkPools struct {
+	Pools  []IsiNetworkPool `json:"pools"`
+	Resume string           `json:"resume"`
+	Total  float64          `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (p *IsiNetworkPools) ResumeToken() string {
+	return p.Resume
+}
+
+type IsiNetworkPool struct {
+	AccessZone  string `json:"access_zone"`
+	AllocMethod string `json:"alloc_method"`
+	Groupnet    string `json:"groupnet"`
+	ID          string `json:"id"`
+	Ifaces      []struct {
+		Iface string  `json:"iface"`
+		Lnn   float64 `json:"lnn"`
+	} `json:"ifaces"`
+	Name   string `json:"name"`
+	Ranges []struct {
+		High string `json:"high"`
+		Low  string `json:"low"`
+	} `json:"ranges"`
+	RebalancePolicy  string `json:"rebalance_policy"`
+	ScConnectPolicy  string `json:"sc_connect_policy"`
+	ScDNSZone        string `json:"sc_dns_zone"`
+	ScFailoverPolicy string `json:"sc_failover_policy"`
+	Subnet           string `json:"subnet"`
+}
+
+type IsiNetworkSubnets struct {
+	Subnets []IsiNetworkSubnet `json:"subnets"`
+	Resume  string             `json:"resume"`
+	Total   float64            `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (s *IsiNetworkSubnets) ResumeToken() string {
+	return s.Resume
+}
+
+type IsiNetworkSubnet struct {
+	AddrFamily string   `json:"addr_family"`
+	Gateway    string   `json:"gateway"`
+	Groupnet   string   `json:"groupnet"`
+	ID         string   `json:"id"`
+	Mtu        float64  `json:"mtu"`
+	Name       string   `json:"name"`
+	Pools      []string `json:"pools"`
+	Prefixlen  float64  `json:"prefixlen"`
+}
+
+type IsiNetworkInterfaces struct {
+	Interfaces []IsiNetworkInterface `json:"interfaces"`
+	Resume     string                `json:"resume"`
+	Total      float64               `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (i *IsiNetworkInterfaces) ResumeToken() string {
+	return i.Resume
+}
+
+type IsiNetworkInterface struct {
+	ID      string   `json:"id"`
+	IPAddrs []string `json:"ip_addrs"`
+	Lnn     float64  `json:"lnn"`
+	Mtu     float64  `json:"mtu"`
+	Name    string   `json:"name"`
+	NicName string   `json:"nic_name"`
+	Owners  []struct {
+		Groupnet string   `json:"groupnet"`
+		IPAddrs  []string `json:"ip_addrs"`
+		Pool     string   `json:"pool"`
+		Subnet   string   `json:"subnet"`
+		Type     string   `json:"type"`
+	} `json:"owners"`
+	Speed  float64 `json:"speed"`
+	Status string  `json:"status"`
+	Type   string  `json:"type"`
+}
+
 type IsiProtoStatTotal struct {
 	InMax   float64 `json:"in_max"`
 	InMin   float64 `json:"in_min"`
@@ -233,8 +537,13 @@ type IsiSyncPolicies struct {
 		TargetSnapshotPattern     string        `json:"target_snapshot_pattern"`
 		WorkersPerNode            float64       `json:"workers_per_node"`
 	} `json:"policies"`
-	Resume interface{} `json:"resume"`
-	Total  int         `json:"total"`
+	Resume string `json:"resume"`
+	Total  int    `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (p *IsiSyncPolicies) ResumeToken() string {
+	return p.Resume
 }
 
 type IsiSnapshotsSummary struct {
@@ -271,6 +580,11 @@ type IsiSnapshots struct {
 	Total float64 `json:"total"`
 }
 
+//ResumeToken implements the Page interface.
+func (s *IsiSnapshots) ResumeToken() string {
+	return s.Resume
+}
+
 type IsiNodesStatus struct {
 	Errors []interface{} `json:"errors"`
 	Nodes  []struct {
@@ -343,6 +657,25 @@ type IsiNodesStatus struct {
 	Total float64 `json:"total"`
 }
 
+type IsiNodesSensors struct {
+	Errors []interface{} `json:"errors"`
+	Nodes  []struct {
+		ID      float64 `json:"id"`
+		Lnn     float64 `json:"lnn"`
+		Sensors []struct {
+			Count  float64 `json:"count"`
+			Name   string  `json:"name"`
+			Values []struct {
+				Desc  string `json:"desc"`
+				Name  string `json:"name"`
+				Units string `json:"units"`
+				Value string `json:"value"`
+			} `json:"values"`
+		} `json:"sensors"`
+	} `json:"nodes"`
+	Total float64 `json:"total"`
+}
+
 type IsiNodesHardware struct {
 	Errors []interface{} `json:"errors"`
 	Nodes  []struct {
@@ -467,71 +800,396 @@ type IsiExportSummary struct {
 	} `json:"summary"`
 }
 
+type IsiNfsExports struct {
+	Exports []IsiNfsExport `json:"exports"`
+	Resume  string         `json:"resume"`
+	Total   float64        `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (e *IsiNfsExports) ResumeToken() string {
+	return e.Resume
+}
+
+type IsiNfsExport struct {
+	AllDirs          bool            `json:"all_dirs"`
+	Clients          []string        `json:"clients"`
+	Description      string          `json:"description"`
+	ID               float64         `json:"id"`
+	MapAll           IsiNfsExportMap `json:"map_all"`
+	MapRoot          IsiNfsExportMap `json:"map_root"`
+	Paths            []string        `json:"paths"`
+	ReadOnly         bool            `json:"read_only"`
+	ReadOnlyClients  []string        `json:"read_only_clients"`
+	ReadWriteClients []string        `json:"read_write_clients"`
+	RootClients      []string        `json:"root_clients"`
+	SecurityFlavors  []string        `json:"security_flavors"`
+	Zone             string          `json:"zone"`
+}
+
+//IsiNfsExportMap is the identity a class of nfs users is mapped to.
+type IsiNfsExportMap struct {
+	Enabled bool `json:"enabled"`
+	User    struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+type IsiNfsExportsCheck struct {
+	Checks []IsiNfsExportCheck `json:"checks"`
+}
+
+type IsiNfsExportCheck struct {
+	ID      float64  `json:"id"`
+	Message string   `json:"message"`
+	Paths   []string `json:"paths"`
+}
+
 type IsiSharesSummary struct {
 	Summary struct {
 		Count float64 `json:"count"`
 	} `json:"summary"`
 }
 
+type IsiSmbShares struct {
+	Shares []IsiSmbShare `json:"shares"`
+	Resume string        `json:"resume"`
+	Total  float64       `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (s *IsiSmbShares) ResumeToken() string {
+	return s.Resume
+}
+
+type IsiSmbShare struct {
+	AccessBasedEnumeration bool   `json:"access_based_enumeration"`
+	ContinuouslyAvailable  bool   `json:"continuously_available"`
+	Description            string `json:"description"`
+	ID                     string `json:"id"`
+	Name                   string `json:"name"`
+	Path                   string `json:"path"`
+	Permissions            []struct {
+		Permission     string     `json:"permission"`
+		PermissionType string     `json:"permission_type"`
+		Trustee        IsiPersona `json:"trustee"`
+	} `json:"permissions"`
+	Zone string `json:"zone"`
+}
+
+type IsiSmbSessions struct {
+	Sessions []IsiSmbSession `json:"sessions"`
+	Resume   string          `json:"resume"`
+	Total    float64         `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (s *IsiSmbSessions) ResumeToken() string {
+	return s.Resume
+}
+
+type IsiSmbSession struct {
+	ActiveTime float64 `json:"active_time"`
+	Client     string  `json:"client"`
+	ClientType string  `json:"client_type"`
+	Computer   string  `json:"computer"`
+	Encryption bool    `json:"encryption"`
+	GuestLogin bool    `json:"guest_login"`
+	ID         float64 `json:"id"`
+	IdleTime   float64 `json:"idle_time"`
+	Openfiles  float64 `json:"openfiles"`
+	User       string  `json:"user"`
+}
+
+type IsiSmbOpenfiles struct {
+	Openfiles []IsiSmbOpenfile `json:"openfiles"`
+	Resume    string           `json:"resume"`
+	Total     float64          `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (o *IsiSmbOpenfiles) ResumeToken() string {
+	return o.Resume
+}
+
+type IsiSmbOpenfile struct {
+	File        string   `json:"file"`
+	ID          float64  `json:"id"`
+	Locks       float64  `json:"locks"`
+	Permissions []string `json:"permissions"`
+	User        string   `json:"user"`
+}
+
+//IsiNumber decodes a number the api returns either as a json number or as a string.
+//A value that is not a number decodes to NaN instead of failing the whole response, check it with Valid.
+type IsiNumber float64
+
+//UnmarshalJSON implements json.Unmarshaler.
+func (n *IsiNumber) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err == nil {
+		*n = IsiNumber(f)
+		return nil
+	}
+	*n = IsiNumber(math.NaN())
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*n = 0
+		return nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		*n = IsiNumber(f)
+	}
+	return nil
+}
+
+//Valid returns false if the api returned something that is not a number.
+func (n IsiNumber) Valid() bool {
+	return !math.IsNaN(float64(n))
+}
+
 type IsiStoragePools struct {
-	Storagepools []struct {
-		Children    []string      `json:"children,omitempty"`
-		HealthFlags []interface{} `json:"health_flags"`
-		ID          float64       `json:"id"`
-		Lnns        []float64     `json:"lnns"`
-		Name        string        `json:"name"`
-		Type        string        `json:"type"`
-		Usage       struct {
-			AvailBytes           string `json:"avail_bytes"`
-			AvailSsdBytes        string `json:"avail_ssd_bytes"`
-			Balanced             bool   `json:"balanced"`
-			FreeBytes            string `json:"free_bytes"`
-			FreeSsdBytes         string `json:"free_ssd_bytes"`
-			TotalBytes           string `json:"total_bytes"`
-			TotalSsdBytes        string `json:"total_ssd_bytes"`
-			VirtualHotSpareBytes string `json:"virtual_hot_spare_bytes"`
-		} `json:"usage"`
-		CanDisableL3     bool   `json:"can_disable_l3,omitempty"`
-		CanEnableL3      bool   `json:"can_enable_l3,omitempty"`
-		L3               bool   `json:"l3,omitempty"`
-		L3Status         string `json:"l3_status,omitempty"`
-		Manual           bool   `json:"manual,omitempty"`
-		ProtectionPolicy string `json:"protection_policy,omitempty"`
-	} `json:"storagepools"`
-	Total float64 `json:"total"`
+	Storagepools []IsiStoragePool `json:"storagepools"`
+	Total        float64          `json:"total"`
+}
+
+type IsiStoragePool struct {
+	Children    []string      `json:"children,omitempty"`
+	HealthFlags []interface{} `json:"health_flags"`
+	ID          float64       `json:"id"`
+	Lnns        []float64     `json:"lnns"`
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Usage       struct {
+		AvailBytes           IsiNumber `json:"avail_bytes"`
+		AvailSsdBytes        IsiNumber `json:"avail_ssd_bytes"`
+		Balanced             bool      `json:"balanced"`
+		FreeBytes            IsiNumber `json:"free_bytes"`
+		FreeSsdBytes         IsiNumber `json:"free_ssd_bytes"`
+		TotalBytes           IsiNumber `json:"total_bytes"`
+		TotalSsdBytes        IsiNumber `json:"total_ssd_bytes"`
+		VirtualHotSpareBytes IsiNumber `json:"virtual_hot_spare_bytes"`
+	} `json:"usage"`
+	CanDisableL3     bool   `json:"can_disable_l3,omitempty"`
+	CanEnableL3      bool   `json:"can_enable_l3,omitempty"`
+	L3               bool   `json:"l3,omitempty"`
+	L3Status         string `json:"l3_status,omitempty"`
+	Manual           bool   `json:"manual,omitempty"`
+	ProtectionPolicy string `json:"protection_policy,omitempty"`
+}
+
+type IsiStoragePoolSettings struct {
+	Settings struct {
+		AutomaticallyManageProtection    string  `json:"automatically_manage_protection"`
+		ProtectDirectoriesOneLevelHigher bool    `json:"protect_directories_one_level_higher"`
+		SpilloverEnabled                 bool    `json:"spillover_enabled"`
+		VirtualHotSpareDenyWrites        bool    `json:"virtual_hot_spare_deny_writes"`
+		VirtualHotSpareHideSpare         bool    `json:"virtual_hot_spare_hide_spare"`
+		VirtualHotSpareLimitDrives       float64 `json:"virtual_hot_spare_limit_drives"`
+		VirtualHotSpareLimitPercent      float64 `json:"virtual_hot_spare_limit_percent"`
+	} `json:"settings"`
+}
+
+type IsiSuggestedProtection struct {
+	SuggestedProtection string `json:"suggested_protection"`
 }
 
 type IsiNodesDrives struct {
 	Errors []interface{} `json:"errors"`
 	Nodes  []struct {
-		Drives []struct {
-			Baynum   float64 `json:"baynum"`
-			Blocks   float64 `json:"blocks"`
-			Chassis  float64 `json:"chassis"`
-			Devname  string  `json:"devname"`
-			Firmware struct {
-				CurrentFirmware string `json:"current_firmware"`
-				DesiredFirmware string `json:"desired_firmware"`
-			} `json:"firmware"`
-			Handle              float64 `json:"handle"`
-			InterfaceType       string  `json:"interface_type"`
-			Lnum                float64 `json:"lnum"`
-			Locnstr             string  `json:"locnstr"`
-			LogicalBlockLength  float64 `json:"logical_block_length"`
-			MediaType           string  `json:"media_type"`
-			Model               string  `json:"model"`
-			PhysicalBlockLength float64 `json:"physical_block_length"`
-			Present             bool    `json:"present"`
-			Purpose             string  `json:"purpose"`
-			PurposeDescription  string  `json:"purpose_description"`
-			Serial              string  `json:"serial"`
-			UIState             string  `json:"ui_state"`
-			Wwn                 string  `json:"wwn"`
-			XLoc                float64 `json:"x_loc"`
-			YLoc                float64 `json:"y_loc"`
-		} `json:"drives"`
-		ID  float64 `json:"id"`
-		Lnn float64 `json:"lnn"`
+		Drives []IsiNodeDrive `json:"drives"`
+		ID     float64        `json:"id"`
+		Lnn    float64        `json:"lnn"`
 	} `json:"nodes"`
 	Total int `json:"total"`
 }
+
+type IsiNodeDrive struct {
+	Baynum   float64 `json:"baynum"`
+	Blocks   float64 `json:"blocks"`
+	Chassis  float64 `json:"chassis"`
+	Devname  string  `json:"devname"`
+	Firmware struct {
+		CurrentFirmware string `json:"current_firmware"`
+		DesiredFirmware string `json:"desired_firmware"`
+	} `json:"firmware"`
+	Handle              float64 `json:"handle"`
+	InterfaceType       string  `json:"interface_type"`
+	Lnum                float64 `json:"lnum"`
+	Locnstr             string  `json:"locnstr"`
+	LogicalBlockLength  float64 `json:"logical_block_length"`
+	MediaType           string  `json:"media_type"`
+	Model               string  `json:"model"`
+	PhysicalBlockLength float64 `json:"physical_block_length"`
+	Present             bool    `json:"present"`
+	Purpose             string  `json:"purpose"`
+	PurposeDescription  string  `json:"purpose_description"`
+	Serial              string  `json:"serial"`
+	UIState             string  `json:"ui_state"`
+	Wwn                 string  `json:"wwn"`
+	XLoc                float64 `json:"x_loc"`
+	YLoc                float64 `json:"y_loc"`
+}
+
+type IsiDriveFirmware struct {
+	Drives []struct {
+		Baynum          float64 `json:"baynum"`
+		CurrentFirmware string  `json:"current_firmware"`
+		DesiredFirmware string  `json:"desired_firmware"`
+		Devname         string  `json:"devname"`
+		Model           string  `json:"model"`
+	} `json:"drives"`
+}
+
+type IsiFilepoolPolicies struct {
+	Policies []IsiFilepoolPolicy `json:"policies"`
+	Total    float64             `json:"total"`
+}
+
+type IsiFilepoolPolicy struct {
+	Actions     []IsiFilepoolAction `json:"actions"`
+	ApplyOrder  float64             `json:"apply_order"`
+	Description string              `json:"description"`
+	ID          float64             `json:"id"`
+	Name        string              `json:"name"`
+	State       string              `json:"state"`
+}
+
+//IsiFilepoolAction is a single setting a file pool policy applies to the files it matches.
+type IsiFilepoolAction struct {
+	ActionParam string `json:"action_param"`
+	ActionType  string `json:"action_type"`
+}
+
+type IsiFilepoolDefaultPolicy struct {
+	DefaultPolicy struct {
+		Actions []IsiFilepoolAction `json:"actions"`
+	} `json:"default-policy"`
+}
+
+type IsiJobReports struct {
+	Reports []IsiJobReport `json:"reports"`
+	Resume  string         `json:"resume"`
+	Total   float64        `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (r *IsiJobReports) ResumeToken() string {
+	return r.Resume
+}
+
+type IsiJobReport struct {
+	ElapsedTime float64 `json:"elapsed_time"`
+	ID          string  `json:"id"`
+	JobID       float64 `json:"job_id"`
+	JobType     string  `json:"job_type"`
+	Phase       float64 `json:"phase"`
+	Results     struct {
+		Policies []IsiSmartPoolsPolicyResult `json:"policies"`
+	} `json:"results"`
+	Time float64 `json:"time"`
+}
+
+//IsiSmartPoolsPolicyResult is what a SmartPools job did for a single file pool policy.
+type IsiSmartPoolsPolicyResult struct {
+	BytesMoved         *IsiNumber `json:"bytes_moved"`
+	DirectoriesMatched struct {
+		Head     IsiNumber `json:"head"`
+		Snapshot IsiNumber `json:"snapshot"`
+	} `json:"directories_matched"`
+	FilesMatched struct {
+		Head     IsiNumber `json:"head"`
+		Snapshot IsiNumber `json:"snapshot"`
+	} `json:"files_matched"`
+	Name string `json:"name"`
+}
+
+type IsiDedupeSummary struct {
+	Summary struct {
+		BlockSize               float64 `json:"block_size"`
+		EstimatedPhysicalBlocks float64 `json:"estimated_physical_blocks"`
+		EstimatedSavedBlocks    float64 `json:"estimated_saved_blocks"`
+		LogicalBlocks           float64 `json:"logical_blocks"`
+		SavedLogicalBlocks      float64 `json:"saved_logical_blocks"`
+		TotalBlocks             float64 `json:"total_blocks"`
+		Used                    float64 `json:"used"`
+	} `json:"summary"`
+}
+
+type IsiDedupeReports struct {
+	Reports []struct {
+		ID      string  `json:"id"`
+		JobID   float64 `json:"job_id"`
+		JobType string  `json:"job_type"`
+		Time    float64 `json:"time"`
+	} `json:"reports"`
+	Resume string  `json:"resume"`
+	Total  float64 `json:"total"`
+}
+
+//ResumeToken implements the Page interface.
+func (r *IsiDedupeReports) ResumeToken() string {
+	return r.Resume
+}
+
+type IsiLicenses struct {
+	Licenses []struct {
+		Duration   float64 `json:"duration"`
+		Expiration string  `json:"expiration"`
+		ID         string  `json:"id"`
+		Name       string  `json:"name"`
+		Status     string  `json:"status"`
+	} `json:"licenses"`
+	Total float64 `json:"total"`
+}
+
+type IsiAuthProvidersSummary struct {
+	ProviderInstances []struct {
+		ActiveServer string `json:"active_server"`
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		Status       string `json:"status"`
+		Type         string `json:"type"`
+		ZoneName     string `json:"zone_name"`
+	} `json:"provider_instances"`
+}
+
+type IsiAdsProviders struct {
+	Ads []struct {
+		ControllerTime          float64 `json:"controller_time"`
+		Hostname                string  `json:"hostname"`
+		ID                      string  `json:"id"`
+		MachinePasswordChanges  bool    `json:"machine_password_changes"`
+		MachinePasswordLifespan float64 `json:"machine_password_lifespan"`
+		Name                    string  `json:"name"`
+		Site                    string  `json:"site"`
+		Status                  string  `json:"status"`
+	} `json:"ads"`
+}
+
+type IsiAdsDomains struct {
+	Domains []struct {
+		DcName    string `json:"dc_name"`
+		Domain    string `json:"domain"`
+		ID        string `json:"id"`
+		Status    string `json:"status"`
+		TrustType string `json:"trust_type"`
+	} `json:"domains"`
+}
+
+type IsiZones struct {
+	Zones []IsiZone `json:"zones"`
+}
+
+type IsiZone struct {
+	AuthProviders []string `json:"auth_providers"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Path          string   `json:"path"`
+	System        bool     `json:"system"`
+	ZoneID        float64  `json:"zone_id"`
+}
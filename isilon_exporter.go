@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/adobe/prometheus-emcisilon-exporter/collector"
+	"github.com/adobe/prometheus-emcisilon-exporter/isiclient"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
@@ -45,11 +46,12 @@ func init() {
 
 // Handler takes care of the local http traffic.
 func handler(w http.ResponseWriter, r *http.Request) {
-	filters := r.URL.Query()["collect[]"]
+	params := r.URL.Query()
+	filters := params["collect[]"]
 	log.Debugln("collect query:", filters)
 
-	//Creates a new isilon collector with filters applied. (Kingpin flags)
-	nc, err := collector.NewIsilonCollector(*fqdn, *port, *uname, *pwdenv, *site, true, *qOnly, filters...)
+	//Creates a new isilon collector with filters and per scrape settings applied. (Kingpin flags)
+	nc, err := collector.NewIsilonCollector(*fqdn, *port, *uname, *pwdenv, *site, true, *qOnly, params, filters...)
 	if err != nil {
 		log.Warnf("Could not create exporter: %s", err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -89,12 +91,16 @@ func main() {
 		metricsPath   = kingpin.Flag("web.telemtry-path", "Path under which to expose metrics.").Default("/metrics").String()
 
 		//Isilon Specific Variables
-		cFQDN     = kingpin.Flag("isilon.cluster.fqdn", "FQDN for the isilon cluster to be scraped.").Default("localhost").String()
-		cPort     = kingpin.Flag("isilon.cluster.port", "Port to connect to the isilon cluster.").Default("8080").String()
-		cUname    = kingpin.Flag("isilon.cluster.username", "Username for access the isilon API.").Default("").String()
-		cPwdenv   = kingpin.Flag("isilon.cluster.password.env", "Environment variable that contains the password for the Isilon cluster user.").Default("ISILON_CLUSTER_PASSWORD").String()
-		cSite     = kingpin.Flag("isilon.cluster.site", "Data Center site the cluster is located in.").Default("").String()
-		quotaOnly = kingpin.Flag("quota-only", "Set exporter to only collect quota information.").Default("false").Bool()
+		cFQDN       = kingpin.Flag("isilon.cluster.fqdn", "FQDN for the isilon cluster to be scraped.").Default("localhost").String()
+		cPort       = kingpin.Flag("isilon.cluster.port", "Port to connect to the isilon cluster.").Default("8080").String()
+		cUname      = kingpin.Flag("isilon.cluster.username", "Username for access the isilon API.").Default("").String()
+		cPwdenv     = kingpin.Flag("isilon.cluster.password.env", "Environment variable that contains the password for the Isilon cluster user.").Default("ISILON_CLUSTER_PASSWORD").String()
+		cSite       = kingpin.Flag("isilon.cluster.site", "Data Center site the cluster is located in.").Default("").String()
+		quotaOnly   = kingpin.Flag("quota-only", "Set exporter to only collect quota information.").Default("false").Bool()
+		pageLimit   = kingpin.Flag("isilon.api.page-limit", "Number of items requested per page from list endpoints (snapshots, quotas, etc.). 0 uses the cluster default.").Default("1000").Int()
+		pageRetries = kingpin.Flag("isilon.api.page-retries", "Number of times a single failed page of a list endpoint is retried.").Default("3").Int()
+		retryDelay  = kingpin.Flag("isilon.api.page-retry-delay", "Base delay between retries of a failed page, multiplied by the attempt number.").Default("1s").Duration()
+		retryWait   = kingpin.Flag("isilon.api.page-retry-max-wait", "Maximum total time spent waiting between the retries of a single failed page.").Default("5s").Duration()
 	)
 
 	log.AddFlags(kingpin.CommandLine)
@@ -113,13 +119,17 @@ func main() {
 	pwdenv = cPwdenv
 	site = cSite
 	qOnly = quotaOnly
+	isiclient.PageLimit = *pageLimit
+	isiclient.PageRetries = *pageRetries
+	isiclient.PageRetryDelay = *retryDelay
+	isiclient.PageRetryMaxWait = *retryWait
 	log.Infoln("Started prometheus-emcisilon-exporter", version.Info())
 
 	log.Infof("Pointed to cluster %s", *fqdn)
 	log.Infoln("Build context", version.BuildContext())
 
 	// This instance is only used to check collector creation and logging.
-	nc, err := collector.NewIsilonCollector(*fqdn, *port, *uname, *pwdenv, *site, false, *qOnly)
+	nc, err := collector.NewIsilonCollector(*fqdn, *port, *uname, *pwdenv, *site, false, *qOnly, nil)
 	if err != nil {
 		log.Fatalf("Could not create collector: %s", err)
 	}